@@ -0,0 +1,131 @@
+package group
+
+import (
+	"fmt"
+	"github.com/XeiTongXueFlyMe/poolgroup/pool"
+	"runtime"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//go test -run=^$ -bench=. -benchmem ./group
+//ns/op 为单个任务的耗时, p50-ns p99-ns 为 g.Go() 到任务开始运行的延迟, goroutines 为任务开始运行时的协程数峰值, running 为同时运行的任务数峰值
+
+var benchSink uint64
+
+var benchTasks = []struct {
+	name string
+	f    func()
+}{
+	{"empty", func() {}},
+	{"cpu", func() {
+		var x uint64
+		for i := uint64(0); i < 2000; i++ {
+			x = x*31 + i
+		}
+		atomic.AddUint64(&benchSink, x)
+	}},
+	{"sleep", func() { time.Sleep(50 * time.Microsecond) }},
+}
+
+var benchStrategies = []struct {
+	name  string
+	setup func(g *Group) (cleanup func())
+}{
+	{"Go", func(g *Group) func() { return func() {} }},
+	{"MaxGoroutine", func(g *Group) func() {
+		g.SetMaxGoroutine(uint64(runtime.NumCPU() * 4))
+		g.ShareMaxGoroutine()
+		return func() {}
+	}},
+	{"Pool", func(g *Group) func() {
+		p := pool.NewPool(uint64(runtime.NumCPU() * 4))
+		g.SetPool(p)
+		return p.Stop
+	}},
+}
+
+//返回提交任务的组
+var benchTrees = []struct {
+	name string
+	fork func(root *Group) []*Group
+}{
+	{"flat", func(root *Group) []*Group {
+		return []*Group{root}
+	}},
+	{"wide", func(root *Group) []*Group {
+		var leaf []*Group
+		for i := 0; i < 16; i++ {
+			leaf = append(leaf, root.ForkChild())
+		}
+		return leaf
+	}},
+	{"deep", func(root *Group) []*Group {
+		node := []*Group{root}
+		for i := 0; i < 8; i++ {
+			node = append(node, node[len(node)-1].ForkChild())
+		}
+		return node
+	}},
+}
+
+func BenchmarkGroup(b *testing.B) {
+	for _, s := range benchStrategies {
+		for _, tree := range benchTrees {
+			for _, task := range benchTasks {
+				s, tree, task := s, tree, task
+				b.Run(fmt.Sprintf("%s/%s/%s", s.name, tree.name, task.name), func(b *testing.B) {
+					g := NewGroup()
+					cleanup := s.setup(g)
+					defer cleanup()
+					groups := tree.fork(g)
+
+					benchRun(b, func(i int, f func() error) {
+						groups[i%len(groups)].Go(f)
+					}, func() { g.Wait() }, task.f)
+				})
+			}
+		}
+	}
+}
+
+//submit 提交第i个任务, wait 等待全部完成
+func benchRun(b *testing.B, submit func(i int, f func() error), wait func(), task func()) {
+	latency := make([]int64, b.N)
+	var running, peakRunning int64
+	peak := int64(runtime.NumGoroutine())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		i, start := i, time.Now()
+		submit(i, func() error {
+			latency[i] = int64(time.Since(start))
+			//每个任务开始时采样, 短时间的运行也能取到峰值
+			benchMax(&peakRunning, atomic.AddInt64(&running, 1))
+			benchMax(&peak, int64(runtime.NumGoroutine()))
+			task()
+			atomic.AddInt64(&running, -1)
+			return nil
+		})
+	}
+	wait()
+	b.StopTimer()
+
+	sort.Slice(latency, func(i, j int) bool { return latency[i] < latency[j] })
+	b.ReportMetric(float64(latency[len(latency)/2]), "p50-ns")
+	b.ReportMetric(float64(latency[len(latency)*99/100]), "p99-ns")
+	b.ReportMetric(float64(atomic.LoadInt64(&peak)), "goroutines")
+	b.ReportMetric(float64(atomic.LoadInt64(&peakRunning)), "running")
+}
+
+func benchMax(peak *int64, n int64) {
+	for {
+		old := atomic.LoadInt64(peak)
+		if n <= old || atomic.CompareAndSwapInt64(peak, old, n) {
+			return
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package group
+
+import (
+	"errors"
+	"fmt"
+	"github.com/XeiTongXueFlyMe/poolgroup/pool"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+)
+
+//任务panic时 TaskError.Err 的类型, 保留了panic的原始值和堆栈
+type PanicError = pool.PanicError
+
+//TaskError 的来源
+type ErrKind int
+
+const (
+	//任务返回的错误
+	KindReturn ErrKind = iota
+	//任务panic
+	KindPanic
+	//回滚函数返回的错误
+	KindRollback
+	//任务被Executor接收后丢弃(如 pool 列队满时的 FullDropOldest), 没有运行; 是暂时性错误
+	KindDropped
+	//WithTimeout() WithTransientTimeout() 的任务超时, Err 为 *TimeoutError
+	KindTimeout
+)
+
+func (k ErrKind) String() string {
+	switch k {
+	case KindReturn:
+		return "return"
+	case KindPanic:
+		return "panic"
+	case KindRollback:
+		return "rollback"
+	case KindDropped:
+		return "dropped"
+	case KindTimeout:
+		return "timeout"
+	}
+	return fmt.Sprintf("ErrKind(%d)", int(k))
+}
+
+//g.Wait() g.GetErrs() 收集到的每一个错误, errors.Is() errors.As() 可以穿透到任务的原始错误
+type TaskError struct {
+	//任务函数名
+	Name string
+	//本组中第几个任务, 从1开始
+	ID uint64
+	//所在组在派生树中的位置, 如 root/0/1 为根组第1个子组的第2个子组
+	Path string
+	Kind ErrKind
+	//任务(回滚时为回滚函数)开始和结束的时间, 没有运行的任务 Start 为零值
+	Start time.Time
+	End   time.Time
+	Err   error
+}
+
+func (e *TaskError) Error() string {
+	switch e.Kind {
+	case KindRollback:
+		return fmt.Sprintf("%s#%d in %s: %s%v", e.Name, e.ID, e.Path, ROLLBACK_ERR, e.Err)
+	case KindReturn, KindTimeout:
+		return fmt.Sprintf("%s#%d in %s: %v", e.Name, e.ID, e.Path, e.Err)
+	}
+	return fmt.Sprintf("%s#%d in %s: %s: %v", e.Name, e.ID, e.Path, e.Kind, e.Err)
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+//被丢弃的任务是暂时性的(负载过高时的削峰), 任务返回的错误按 IsTransient() 判断, panic 和回滚失败都是致命的
+func (e *TaskError) Transient() bool {
+	if e.Kind == KindDropped {
+		return true
+	}
+	return (e.Kind == KindReturn || e.Kind == KindTimeout) && IsTransient(e.Err)
+}
+
+//暂时性错误实现的接口, 如限流, 可以重试, 不取消同组的其他任务也不回滚
+type TransientError interface {
+	error
+	Transient() bool
+}
+
+type transientError struct {
+	err error
+}
+
+//把err标记为暂时性错误, err为nil时返回nil
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &transientError{err: err}
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transientError) Unwrap() error {
+	return e.err
+}
+
+func (e *transientError) Transient() bool {
+	return true
+}
+
+//err的错误链中第一个实现了 TransientError 的错误是否为暂时性错误, 没有实现的为致命错误
+func IsTransient(err error) bool {
+	var te TransientError
+	return errors.As(err, &te) && te.Transient()
+}
+
+//g.WaitErr() 返回的错误, 按派生树分组, errors.Is() errors.As() 会检查其中每一个错误
+type GroupError struct {
+	//组在派生树中的位置, 同 TaskError.Path
+	Path string
+	//本组的错误, 包括回滚的错误
+	Errs []error
+	//有错误的子组
+	Child []*GroupError
+}
+
+func (e *GroupError) Error() string {
+	var b strings.Builder
+	e.write(&b, "")
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (e *GroupError) write(b *strings.Builder, indent string) {
+	fmt.Fprintf(b, "%s%s: %d errors\n", indent, e.Path, len(e.Errs))
+	for _, err := range e.Errs {
+		fmt.Fprintf(b, "%s  %v\n", indent, err)
+	}
+	for _, c := range e.Child {
+		c.write(b, indent+"  ")
+	}
+}
+
+func (e *GroupError) Unwrap() []error {
+	errs := append([]error(nil), e.Errs...)
+	for _, c := range e.Child {
+		errs = append(errs, c)
+	}
+	return errs
+}
+
+//按 TaskError.Path 把 g.Wait() 的结果还原成树, 没有错误时返回nil
+func joinErrs(root string, errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	top := &GroupError{Path: root}
+	nodes := map[string]*GroupError{root: top}
+	var node func(path string) *GroupError
+	node = func(path string) *GroupError {
+		if n, ok := nodes[path]; ok {
+			return n
+		}
+		n := &GroupError{Path: path}
+		parent := node(path[:strings.LastIndex(path, "/")])
+		parent.Child = append(parent.Child, n)
+		nodes[path] = n
+		return n
+	}
+
+	for _, e := range errs {
+		path := root
+		var te *TaskError
+		if errors.As(e, &te) && strings.HasPrefix(te.Path, root+"/") {
+			path = te.Path
+		}
+		n := node(path)
+		n.Errs = append(n.Errs, e)
+	}
+	return top
+}
+
+//任务返回的错误
+func (g *Group) returnErr(t *task, start time.Time, err error) *TaskError {
+	var te *TimeoutError
+	if errors.As(err, &te) {
+		return g.taskErr(t, KindTimeout, start, err)
+	}
+	return g.taskErr(t, KindReturn, start, err)
+}
+
+func (g *Group) taskErr(t *task, kind ErrKind, start time.Time, err error) *TaskError {
+	return &TaskError{
+		Name:  t.getName(),
+		ID:    t.id,
+		Path:  g.path,
+		Kind:  kind,
+		Start: start,
+		End:   time.Now(),
+		Err:   err,
+	}
+}
+
+//只在出错时取函数名
+func (t *task) getName() string {
+	if t.name != "" {
+		return t.name
+	}
+	if t.fn != nil {
+		return funcName(t.fn)
+	}
+	return funcName(t.f)
+}
+
+func funcName(f interface{}) string {
+	if v := reflect.ValueOf(f); v.Kind() == reflect.Func {
+		if fn := runtime.FuncForPC(v.Pointer()); fn != nil {
+			return fn.Name()
+		}
+	}
+	return "unknown"
+}
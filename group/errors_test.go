@@ -0,0 +1,245 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errTest = errors.New("test")
+
+func testReturn() error {
+	time.Sleep(time.Millisecond)
+	return errTest
+}
+
+func testPanic() error {
+	panic("panic")
+}
+
+func TestTaskError(t *testing.T) {
+	before := time.Now()
+
+	g := NewGroup()
+	c := g.ForkChild().ForkChild()
+	assert.NoError(t, c.Go(func() error { return nil }))
+	assert.NoError(t, c.Go(testReturn))
+	assert.NoError(t, g.Go(testPanic))
+
+	errs := g.Wait()
+	assert.Len(t, errs, 2)
+
+	var te *TaskError
+	assert.True(t, errors.As(errs[0], &te))
+	assert.True(t, errors.Is(errs[0], errTest))
+	assert.Equal(t, KindReturn, te.Kind)
+	assert.Equal(t, "root/0/0", te.Path)
+	assert.EqualValues(t, 2, te.ID)
+	assert.Equal(t, "github.com/XeiTongXueFlyMe/poolgroup/group.testReturn", te.Name)
+	assert.True(t, !te.Start.Before(before))
+	assert.True(t, te.End.Sub(te.Start) >= time.Millisecond)
+	assert.Equal(t, "github.com/XeiTongXueFlyMe/poolgroup/group.testReturn#2 in root/0/0: test", te.Error())
+
+	assert.True(t, errors.As(errs[1], &te))
+	assert.Equal(t, KindPanic, te.Kind)
+	assert.Equal(t, "root", te.Path)
+	assert.EqualError(t, te.Err, "panic")
+	var pe *PanicError
+	assert.True(t, errors.As(errs[1], &pe))
+	assert.Equal(t, "panic", pe.Value)
+	assert.Contains(t, string(pe.Stack), "testPanic")
+	assert.Equal(t, "github.com/XeiTongXueFlyMe/poolgroup/group.testPanic#1 in root: panic: panic", te.Error())
+}
+
+func TestTaskErrorRollback(t *testing.T) {
+	g := NewGroup()
+	g.WithContext(context.TODO())
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error { return nil }, WithRollback(testReturn)))
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error { return errors.New("err") }))
+
+	errs := g.Wait()
+	assert.Len(t, errs, 2)
+
+	//回滚的错误在任务的错误之前
+	var te *TaskError
+	assert.True(t, errors.As(errs[0], &te))
+	assert.True(t, errors.Is(errs[0], errTest))
+	assert.Equal(t, KindRollback, te.Kind)
+	assert.EqualValues(t, 1, te.ID)
+	assert.Contains(t, te.Error(), ROLLBACK_ERR)
+
+	assert.True(t, errors.As(errs[1], &te))
+	assert.Equal(t, KindReturn, te.Kind)
+	assert.EqualValues(t, 2, te.ID)
+}
+
+func TestTaskErrorDropped(t *testing.T) {
+	g := NewGroup()
+	g.SetExecutor(dropExecutor{})
+	assert.NoError(t, g.Go(testReturn))
+
+	errs := g.Wait()
+	assert.Len(t, errs, 1)
+
+	var te *TaskError
+	assert.True(t, errors.As(errs[0], &te))
+	assert.Equal(t, KindDropped, te.Kind)
+	assert.True(t, te.Start.IsZero())
+	assert.True(t, te.Transient())
+	assert.EqualError(t, te.Err, "drop")
+	assert.Contains(t, te.Error(), "dropped: drop")
+}
+
+func TestTaskErrorPanicValue(t *testing.T) {
+	g := NewGroup()
+	g.WithContext(context.TODO())
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error { panic(errTest) }))
+
+	errs := g.Wait()
+	assert.Len(t, errs, 1)
+	//panic(err) 的err可以穿透
+	assert.True(t, errors.Is(errs[0], errTest))
+
+	var pe *PanicError
+	assert.True(t, errors.As(errs[0], &pe))
+	assert.Equal(t, errTest, pe.Value)
+	assert.Contains(t, string(pe.Stack), "TestTaskErrorPanicValue")
+}
+
+func TestErrKindString(t *testing.T) {
+	assert.Equal(t, "return", KindReturn.String())
+	assert.Equal(t, "panic", KindPanic.String())
+	assert.Equal(t, "rollback", KindRollback.String())
+	assert.Equal(t, "dropped", KindDropped.String())
+	assert.Equal(t, "timeout", KindTimeout.String())
+	assert.Equal(t, "ErrKind(9)", ErrKind(9).String())
+}
+
+func TestWaitErr(t *testing.T) {
+	g := NewGroup()
+	A := g.ForkChild()
+	B := g.ForkChild()
+	b := B.ForkChild()
+	assert.NoError(t, g.Go(func() error { return errors.New("g") }))
+	assert.NoError(t, A.Go(func() error { return nil }))
+	assert.NoError(t, b.Go(testReturn))
+	assert.NoError(t, b.Go(func() error { panic(context.Canceled) }))
+
+	err := g.WaitErr()
+	var ge *GroupError
+	assert.True(t, errors.As(err, &ge))
+	assert.True(t, errors.Is(err, errTest))
+	assert.True(t, errors.Is(err, context.Canceled))
+	var pe *PanicError
+	assert.True(t, errors.As(err, &pe))
+
+	//没有错误的组不出现
+	assert.Equal(t, "root", ge.Path)
+	assert.Len(t, ge.Errs, 1)
+	assert.Len(t, ge.Child, 1)
+	assert.Equal(t, "root/1", ge.Child[0].Path)
+	assert.Len(t, ge.Child[0].Errs, 0)
+	assert.Equal(t, "root/1/0", ge.Child[0].Child[0].Path)
+	assert.Len(t, ge.Child[0].Child[0].Errs, 2)
+
+	lines := strings.Split(err.Error(), "\n")
+	assert.Len(t, lines, 6)
+	assert.Equal(t, "root: 1 errors", lines[0])
+	assert.Equal(t, "  root/1: 0 errors", lines[2])
+	assert.Equal(t, "    root/1/0: 2 errors", lines[3])
+
+	//在子组上调用
+	c := NewGroup().ForkChild()
+	assert.NoError(t, c.Go(testReturn))
+	err = c.WaitErr()
+	assert.True(t, errors.As(err, &ge))
+	assert.Equal(t, "root/0", ge.Path)
+	assert.Len(t, ge.Errs, 1)
+
+	assert.NoError(t, NewGroup().WaitErr())
+}
+
+type throttled struct{}
+
+func (throttled) Error() string   { return "throttled" }
+func (throttled) Transient() bool { return true }
+
+func TestIsTransient(t *testing.T) {
+	assert.Nil(t, Transient(nil))
+	assert.False(t, IsTransient(nil))
+	assert.False(t, IsTransient(errTest))
+
+	err := Transient(errTest)
+	assert.True(t, IsTransient(err))
+	assert.True(t, errors.Is(err, errTest))
+	assert.EqualError(t, err, "test")
+	assert.True(t, IsTransient(fmt.Errorf("wrap: %w", err)))
+	assert.True(t, IsTransient(throttled{}))
+
+	//最后一次的错误决定
+	assert.True(t, IsTransient(&RetryError{Errs: []error{errTest, err}}))
+	assert.False(t, IsTransient(&RetryError{Errs: []error{err, errTest}}))
+
+	//panic的错误是致命的
+	assert.False(t, IsTransient(&TaskError{Kind: KindPanic, Err: err}))
+	assert.True(t, IsTransient(&TaskError{Kind: KindReturn, Err: err}))
+}
+
+func TestTransientNoCancel(t *testing.T) {
+	f := class{a: 1}
+
+	g := NewGroup()
+	g.WithContext(context.TODO())
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error { return nil }, WithRollback(f.funcResetA)))
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error { return throttled{} }))
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(20 * time.Millisecond):
+			return Transient(errTest)
+		}
+	}))
+
+	//暂时性错误记录下来, 但不取消其他任务, 不回滚
+	errs := g.Wait()
+	assert.Len(t, errs, 2)
+	for _, e := range errs {
+		assert.True(t, IsTransient(e))
+	}
+	assert.Equal(t, 1, f.a)
+
+	//致命错误照旧取消并回滚
+	g = NewGroup()
+	c := g.WithContext(context.TODO())
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error { return Transient(errTest) }, WithRollback(f.funcResetA)))
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error { return errTest }))
+	errs = g.Wait()
+	assert.Len(t, errs, 2)
+	assert.Equal(t, context.Canceled, c.Err())
+	assert.Equal(t, 0, f.a)
+}
+
+func TestTransientRetry(t *testing.T) {
+	var count int64
+
+	g := NewGroup()
+	g.WithContext(context.TODO())
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error {
+		if atomic.AddInt64(&count, 1) < 3 {
+			return Transient(errTest)
+		}
+		return errors.New("fatal")
+	}, WithRetry(Retry{MaxAttempts: 5, RetryIf: IsTransient})))
+
+	//致命错误不再重试
+	errs := g.Wait()
+	assert.EqualValues(t, 3, count)
+	assert.Len(t, errs, 1)
+	assert.False(t, IsTransient(errs[0]))
+}
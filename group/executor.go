@@ -0,0 +1,35 @@
+package group
+
+//Executor 决定 g.Go() 提交的任务在哪里运行
+//*pool.Pool 实现了 Executor
+type Executor interface {
+	//返回错误时 task 不会运行; 已接收的 task 之后被丢弃时, 不运行 task, 改为调用 drop
+	Execute(task func(), drop func(err error)) error
+}
+
+//Executor 中的任务按优先级排队时实现, priority 高的先运行
+type PriorityExecutor interface {
+	Executor
+	ExecutePriority(priority int, task func(), drop func(err error)) error
+}
+
+var (
+	//每个任务创建一个协程, NewGroup()的默认值
+	GoroutineExecutor Executor = goroutineExecutor{}
+	//任务在调用 g.Go() 的协程中同步运行, g.Go()返回时任务已结束, 适用于确定性的单元测试
+	SyncExecutor Executor = syncExecutor{}
+)
+
+type goroutineExecutor struct{}
+
+func (goroutineExecutor) Execute(task func(), drop func(err error)) error {
+	go task()
+	return nil
+}
+
+type syncExecutor struct{}
+
+func (syncExecutor) Execute(task func(), drop func(err error)) error {
+	task()
+	return nil
+}
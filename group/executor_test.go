@@ -0,0 +1,139 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"github.com/XeiTongXueFlyMe/poolgroup/pool"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSyncExecutor(t *testing.T) {
+	var order []int
+
+	g := NewGroup()
+	g.SetExecutor(SyncExecutor)
+	assert.NoError(t, g.Go(func() error { order = append(order, 1); return nil }))
+	assert.NoError(t, g.Go(func() error { order = append(order, 2); return errors.New("err") }))
+
+	//子组继承Executor
+	A := g.ForkChild()
+	assert.NoError(t, A.Go(func() error { order = append(order, 3); panic("panic") }))
+
+	//任务在g.Go()返回前已经结束
+	assert.Equal(t, []int{1, 2, 3}, order)
+	assert.Len(t, g.GetErrs(), 2)
+	assert.Len(t, g.Wait(), 2)
+}
+
+func TestExecutorSubtree(t *testing.T) {
+	f := class{}
+	p := pool.NewPool(4)
+	defer p.Stop()
+
+	g := NewGroup()
+	assert.NoError(t, g.Go(f.funcA))
+
+	A := g.ForkChild()
+	A.SetPool(p)
+	assert.NoError(t, A.Go(f.funcB))
+
+	B := g.ForkChild()
+	B.SetExecutor(SyncExecutor)
+	assert.NoError(t, B.Go(f.funcC))
+	assert.EqualValues(t, 1, f.c)
+
+	a := A.ForkChild()
+	assert.NoError(t, a.Go(f.funcB))
+
+	assert.Empty(t, g.Wait())
+	assert.Empty(t, p.Wait())
+	assert.EqualValues(t, 1, f.a)
+	assert.EqualValues(t, 2, f.b)
+}
+
+type rejectExecutor struct{}
+
+func (rejectExecutor) Execute(task func(), drop func(err error)) error {
+	return errors.New("reject")
+}
+
+//接收任务后丢弃
+type dropExecutor struct{}
+
+func (dropExecutor) Execute(task func(), drop func(err error)) error {
+	go drop(errors.New("drop"))
+	return nil
+}
+
+func TestExecutorReject(t *testing.T) {
+	g := NewGroup()
+	g.SetExecutor(rejectExecutor{})
+	assert.EqualError(t, g.Go(func() error { return nil }), "reject")
+	//只返回给调用者
+	assert.Len(t, g.Wait(), 0)
+}
+
+//拒绝或丢弃任务不取消ctx, 也不回滚
+func TestExecutorRejectNoCancel(t *testing.T) {
+	for _, policy := range []pool.FullPolicy{pool.FullReject, pool.FullDropNewest, pool.FullDropOldest} {
+		p := pool.NewPool(1)
+		p.SetQueueSize(1, policy)
+
+		f := class{a: 1}
+		block, started := make(chan struct{}), make(chan struct{})
+		g := NewGroup()
+		g.WithContext(context.TODO())
+		g.SetPool(p)
+		assert.NoError(t, g.Go(func() error { return nil }, WithRollback(f.funcResetA)))
+		p.Wait()
+		assert.NoError(t, g.Go(func() error {
+			close(started)
+			<-block
+			return nil
+		}))
+		<-started
+		//一个在列队中, 一个被拒绝或丢弃
+		g.GoCtx(func(ctx context.Context) error { return nil })
+		g.GoCtx(func(ctx context.Context) error { return nil })
+
+		close(block)
+		p.Wait()
+		assert.NoError(t, (*g.ctx).Err(), policy)
+		for _, e := range g.Wait() {
+			assert.True(t, errors.Is(e, pool.ErrTaskDropped))
+			assert.True(t, IsTransient(e))
+		}
+		assert.Equal(t, 1, f.a, policy)
+		p.Stop()
+	}
+}
+
+func TestExecutorPoolDrop(t *testing.T) {
+	p := pool.NewPool(1)
+	defer p.Stop()
+	p.SetQueueSize(1, pool.FullDropNewest)
+
+	block, started := make(chan struct{}), make(chan struct{})
+	assert.NoError(t, p.Submit(func() error {
+		close(started)
+		<-block
+		return nil
+	}))
+	<-started
+
+	g := NewGroup()
+	g.SetPool(p)
+	assert.NoError(t, g.Go(func() error { return nil }))
+	assert.NoError(t, g.Go(func() error { return nil }))
+
+	p.SetQueueSize(1, pool.FullReject)
+	assert.Equal(t, pool.ErrPoolFull, g.Go(func() error { return nil }))
+
+	close(block)
+	//被丢弃的任务不会让 g.Wait() 阻塞
+	errs := g.Wait()
+	assert.Len(t, errs, 1)
+	assert.True(t, errors.Is(errs[0], pool.ErrTaskDropped))
+	assert.EqualValues(t, 3, g.GetGoroutineNum())
+}
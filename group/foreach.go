@@ -0,0 +1,65 @@
+package group
+
+import (
+	"context"
+)
+
+//对items中的每一项并发调用fn, 同时运行的不超过limit个, limit为0时不限制
+//任何一项返回错误或panic: ctx被取消, 尚未开始的项不再运行, 已完成的项调用rollback回滚
+//阻塞直到全部结束, 返回第一个错误; 没有项出错但ctx先结束时返回 ctx.Err()
+func ForEach[T any](ctx context.Context, items []T, limit uint64, fn func(ctx context.Context, item T) error, rollback ...func(item T) error) error {
+	return forEach(ctx, len(items), limit, func(ctx context.Context, i int) error {
+		return fn(ctx, items[i])
+	}, bindRollback(items, rollback))
+}
+
+//同 ForEach(), 返回的结果与items按下标一一对应
+//出错时仍返回已运行项的结果, 未运行的项为R的零值
+func Map[T, R any](ctx context.Context, items []T, limit uint64, fn func(ctx context.Context, item T) (R, error), rollback ...func(item T) error) ([]R, error) {
+	res := make([]R, len(items))
+	err := forEach(ctx, len(items), limit, func(ctx context.Context, i int) (e error) {
+		res[i], e = fn(ctx, items[i])
+		return
+	}, bindRollback(items, rollback))
+
+	return res, err
+}
+
+//第i项的回滚函数
+func bindRollback[T any](items []T, rollback []func(item T) error) func(i int) []func() error {
+	return func(i int) []func() error {
+		var r []func() error
+		for _, f := range rollback {
+			f := f
+			r = append(r, func() error { return f(items[i]) })
+		}
+		return r
+	}
+}
+
+func forEach(ctx context.Context, n int, limit uint64, fn func(ctx context.Context, i int) error, rollback func(i int) []func() error) error {
+	g := NewGroup()
+	c := g.WithContext(ctx)
+	g.SetMaxGoroutine(limit)
+
+	var err error
+	for i := 0; i < n; i++ {
+		i := i
+		//已出错或ctx结束, 不再提交
+		if err = g.GoCtx(func(ctx context.Context) error {
+			return fn(ctx, i)
+		}, WithWaitContext(c), WithRollback(rollback(i)...)); err != nil {
+			break
+		}
+	}
+
+	errs := g.Wait()
+	//优先返回任务的错误, 其次是回滚的错误
+	if e := g.GetErrs(); len(e) > 0 {
+		return e[0]
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return err
+}
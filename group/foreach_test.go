@@ -0,0 +1,126 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForEach(t *testing.T) {
+	var running, peak, sum int64
+
+	items := []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	err := ForEach(context.TODO(), items, 3, func(ctx context.Context, item int64) error {
+		n := atomic.AddInt64(&running, 1)
+		defer atomic.AddInt64(&running, -1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt64(&sum, item)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(55), sum)
+	assert.True(t, peak <= 3)
+}
+
+func TestForEachErr(t *testing.T) {
+	var m sync.Mutex
+	var started, rollback []int
+
+	items := []int{0, 1, 2, 3, 4, 5, 6, 7}
+	err := ForEach(context.TODO(), items, 1, func(ctx context.Context, item int) error {
+		m.Lock()
+		started = append(started, item)
+		m.Unlock()
+
+		if item == 2 {
+			return errors.New("err")
+		}
+		return nil
+	}, func(item int) error {
+		m.Lock()
+		rollback = append(rollback, item)
+		m.Unlock()
+		return nil
+	})
+
+	assert.EqualError(t, errors.Unwrap(err), "err")
+	//出错后不再运行剩余的项
+	assert.Equal(t, []int{0, 1, 2}, started)
+	assert.ElementsMatch(t, []int{0, 1, 2}, rollback)
+}
+
+//回滚函数超过 ROLLBACK_MAXNUM 个时不阻塞
+func TestForEachLarge(t *testing.T) {
+	items := make([]int, ROLLBACK_MAXNUM+50)
+	for i := range items {
+		items[i] = i
+	}
+
+	var rollback int64
+	done := make(chan error)
+	go func() {
+		done <- ForEach(context.TODO(), items, 0, func(ctx context.Context, item int) error {
+			if item == len(items)-1 {
+				return errTest
+			}
+			return nil
+		}, func(item int) error {
+			atomic.AddInt64(&rollback, 1)
+			return nil
+		})
+	}()
+
+	select {
+	case err := <-done:
+		assert.True(t, errors.Is(err, errTest))
+	case <-time.After(10 * time.Second):
+		t.Fatal("ForEach() blocked")
+	}
+	assert.EqualValues(t, len(items), rollback)
+}
+
+func TestForEachCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var n int64
+	err := ForEach(ctx, []int{1, 2, 3}, 0, func(ctx context.Context, item int) error {
+		atomic.AddInt64(&n, 1)
+		return nil
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, int64(0), n)
+}
+
+func TestMap(t *testing.T) {
+	items := []int{5, 4, 3, 2, 1}
+	res, err := Map(context.TODO(), items, 2, func(ctx context.Context, item int) (string, error) {
+		//越早的项越晚结束
+		time.Sleep(time.Duration(item) * 5 * time.Millisecond)
+		return string(rune('a' + item)), nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"f", "e", "d", "c", "b"}, res)
+
+	res, err = Map(context.TODO(), items, 1, func(ctx context.Context, item int) (string, error) {
+		if item == 4 {
+			panic("panic")
+		}
+		return "ok", nil
+	})
+	assert.EqualError(t, errors.Unwrap(err), "panic")
+	assert.Equal(t, []string{"ok", "", "", "", ""}, res)
+}
@@ -0,0 +1,56 @@
+package group
+
+import (
+	"context"
+)
+
+//g.GoFuture() 提交的单个任务的句柄
+type Future struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+func newFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+//任务结束(返回，panic，或被Executor丢弃)时关闭
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+//阻塞直到任务结束，返回任务的错误；ctx先结束时返回 ctx.Err()，任务继续运行
+func (f *Future) Wait(ctx context.Context) error {
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+//任务的错误，任务结束前为nil
+func (f *Future) Err() error {
+	select {
+	case <-f.done:
+		return f.err
+	default:
+		return nil
+	}
+}
+
+//g.GoFuture() g.GoFutureCtx() 的f返回的值，任务结束前为nil
+func (f *Future) Value() interface{} {
+	select {
+	case <-f.done:
+		return f.value
+	default:
+		return nil
+	}
+}
+
+func (f *Future) resolve(err error) {
+	f.err = err
+	close(f.done)
+}
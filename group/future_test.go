@@ -0,0 +1,101 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"github.com/XeiTongXueFlyMe/poolgroup/pool"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestFuture(t *testing.T) {
+	block := make(chan struct{})
+
+	g := NewGroup()
+	slow, err := g.GoFuture(func() (interface{}, error) {
+		<-block
+		return 42, nil
+	})
+	assert.NoError(t, err)
+	failed, err := g.GoFuture(func() (interface{}, error) { return nil, errors.New("err") })
+	assert.NoError(t, err)
+	panicked, err := g.GoFuture(func() (interface{}, error) { panic("panic") })
+	assert.NoError(t, err)
+
+	//只等待其中一个任务
+	assert.EqualError(t, failed.Wait(context.TODO()), "err")
+	assert.EqualError(t, panicked.Wait(context.TODO()), "panic")
+	assert.Nil(t, failed.Value())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, slow.Wait(ctx))
+	assert.Nil(t, slow.Err())
+	assert.Nil(t, slow.Value())
+
+	close(block)
+	<-slow.Done()
+	assert.NoError(t, slow.Err())
+	assert.Equal(t, 42, slow.Value())
+
+	assert.Len(t, g.Wait(), 2)
+}
+
+func TestFutureContext(t *testing.T) {
+	f := class{}
+
+	g := NewGroup()
+	g.WithContext(context.TODO())
+	fu, err := g.GoFutureCtx(func(ctx context.Context) (interface{}, error) {
+		return "ok", nil
+	}, WithRollback(f.funcResetA))
+	assert.NoError(t, err)
+	assert.NoError(t, fu.Wait(context.TODO()))
+	assert.Equal(t, "ok", fu.Value())
+
+	fu, err = g.GoFutureCtx(func(ctx context.Context) (interface{}, error) {
+		return nil, f.funcTimeOut(ctx)
+	}, WithName("timeout"))
+	assert.NoError(t, err)
+	assert.EqualError(t, fu.Wait(context.TODO()), "time out")
+
+	//上下文组中也可以使用 g.GoFuture()
+	fu, err = g.GoFuture(func() (interface{}, error) { return 1, nil })
+	assert.NoError(t, err)
+	assert.NoError(t, fu.Wait(context.TODO()))
+	assert.Equal(t, 1, fu.Value())
+
+	errs := g.Wait()
+	assert.Len(t, errs, 1)
+	var te *TaskError
+	assert.True(t, errors.As(errs[0], &te))
+	assert.Equal(t, "timeout", te.Name)
+}
+
+func TestFutureDropped(t *testing.T) {
+	p := pool.NewPool(1)
+	defer p.Stop()
+	p.SetQueueSize(1, pool.FullDropOldest)
+
+	block, started := make(chan struct{}), make(chan struct{})
+	g := NewGroup()
+	g.SetPool(p)
+	assert.NoError(t, g.Go(func() error {
+		close(started)
+		<-block
+		return nil
+	}))
+	<-started
+
+	first, err := g.GoFuture(func() (interface{}, error) { return nil, nil })
+	assert.NoError(t, err)
+	second, err := g.GoFuture(func() (interface{}, error) { return nil, nil })
+	assert.NoError(t, err)
+
+	//被丢弃的任务也会结束
+	assert.Equal(t, pool.ErrTaskDropped, first.Wait(context.TODO()))
+	close(block)
+	assert.NoError(t, second.Wait(context.TODO()))
+	g.Wait()
+}
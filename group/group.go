@@ -4,42 +4,154 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/XeiTongXueFlyMe/poolgroup/pool"
+	"os"
 	"sync"
 	"time"
 )
 
 const (
+	//Deprecated: 回滚函数不再有数量上限
 	ROLLBACK_MAXNUM     = 10000
 	GO_F_TYPE_ERR       = "g.Go(f): f.(type) is FAILURE"
+	GO_WEIGHT_ERR       = "g.GoWeighted(weight): weight is greater than MaxGoroutine"
+	GROUP_FULL_ERR      = "g.TryGo(f): MaxGoroutine is reached"
 	FUNC_CALL_LOGIC_ERR = "err :calling Configure after calling g.Go()"
 	ROLLBACK_ERR        = "rollback ERR: "
 )
 
+//g.TryGo() 没有空闲位置
+var ErrGroupFull = errors.New(GROUP_FULL_ERR)
+
+//WithWeight() 的weight大于本组(含共享的祖先)的预算, 任务没有提交
+var ErrWeightTooHeavy = errors.New(GO_WEIGHT_ERR)
+
+//g.GoFunc() 等 interface{} 入口的f不是 func() error 或 func(ctx context.Context) error, 任务没有提交
+var ErrFuncType = errors.New(GO_F_TYPE_ERR)
+
+//任务panic时的处理方式
+type PanicPolicy int
+
+const (
+	//recover 并记录到 errs, 默认值
+	PanicRecover PanicPolicy = iota
+	//recover 并记录到 errs, g.Wait() 结束时在调用者的协程中重新panic, panic的值为第一个 *TaskError
+	PanicRepanic
+	//打印堆栈后进程立即崩溃, Executor(如 *pool.Pool)也无法recover
+	PanicCrash
+)
+
+//任务出错时何时取消本组的ctx并回滚, 见 g.SetErrorPolicy()
+type ErrorPolicy struct {
+	n       uint64
+	ratio   float64
+	min     uint64
+	isRatio bool
+}
+
+var (
+	//第一个错误即取消ctx并回滚, 默认值
+	FailFast = ErrorPolicy{n: 1}
+	//只收集错误, 即使是上下文组也不取消ctx, 不回滚
+	CollectAll = ErrorPolicy{}
+)
+
+//第n个错误时取消ctx并回滚, n为0时同 CollectAll
+func CancelAfter(n uint64) ErrorPolicy {
+	return ErrorPolicy{n: n}
+}
+
+//至少min个任务结束后, 出错任务的比例超过ratio时取消ctx并回滚
+//g.Wait() 时不论结束的任务是否达到min, 按全部任务的比例判断是否回滚
+func CancelAboveRatio(ratio float64, min uint64) ErrorPolicy {
+	return ErrorPolicy{ratio: ratio, min: min, isRatio: true}
+}
+
+//failed 个出错, 共 done 个任务结束, isEnd 为全部任务已结束
+func (p ErrorPolicy) trip(failed, done uint64, isEnd bool) bool {
+	if p.isRatio {
+		return done > 0 && (isEnd || done >= p.min) && float64(failed)/float64(done) > p.ratio
+	}
+	return p.n != 0 && failed >= p.n
+}
+
 type Group struct {
 	child []*Group
+	path  string
 
 	isUsed     bool
-	max        uint64
-	counter    uint64
 	total      uint64
-	do         chan bool
+	limiter    limiter
+	isShared   bool
 	errs       []error
 	m          sync.Mutex
 	wg         sync.WaitGroup
 	ctx        *context.Context
 	cancel     context.CancelFunc
 	isRollback bool
-	rollback   chan func() error
+	rollbacks  []rollbackFunc
+	executor   Executor
+
+	panicPolicy PanicPolicy
+	onPanic     func(e *TaskError)
+	panicked    *TaskError
+
+	errPolicy ErrorPolicy
+	//已结束的任务数
+	done      uint64
+	transient uint64
+	isTripped bool
+}
+
+//一次 g.Go() 提交的任务
+type task struct {
+	f        interface{}
+	rollback []interface{}
+	weight   uint64
+	priority int
+	future   *Future
+	name     string
+	id       uint64
+	//用户提交的函数, 用于取任务名
+	fn      interface{}
+	timeout time.Duration
+	//超时为暂时性错误, 见 WithTransientTimeout()
+	isTransientTimeout bool
+	retry              *Retry
+	//等待空闲位置时使用, 见 WithWaitContext()
+	waitCtx context.Context
+}
+
+//任务注册的回滚函数
+type rollbackFunc struct {
+	f func() error
+	t *task
+}
+
+//任务结束, err为任务最终的错误
+func (t *task) finish(err error) {
+	if t.future != nil {
+		t.future.resolve(err)
+	}
 }
 
 func NewGroup() *Group {
-	return &Group{do: make(chan bool), rollback: make(chan func() error, ROLLBACK_MAXNUM)}
+	return &Group{path: "root", executor: GoroutineExecutor, errPolicy: FailFast}
 }
 func (g *Group) ForkChild() *Group {
 	g.m.Lock()
 	defer g.m.Unlock()
 
 	child := NewGroup()
+	child.path = fmt.Sprintf("%s/%d", g.path, len(g.child))
+	child.executor = g.executor
+	child.panicPolicy = g.panicPolicy
+	child.onPanic = g.onPanic
+	child.errPolicy = g.errPolicy
+	if g.isShared {
+		child.isShared = true
+		child.limiter.parent = &g.limiter
+	}
 	if g.ctx != nil {
 		ctx, cancel := context.WithCancel(*g.ctx)
 		child.ctx = &ctx
@@ -66,18 +178,70 @@ func (g *Group) WithTimeout(ctx context.Context, timeout time.Duration) (c conte
 	return
 }
 
-func (g *Group) SetMaxGoroutine(n uint64) {
+//本组及之后派生的子组, 任务由e运行, 子组可以再设置自己的Executor
+func (g *Group) SetExecutor(e Executor) {
+	g.checkCallLogic()
+
+	g.m.Lock()
+	defer g.m.Unlock()
+
+	g.executor = e
+}
+
+//本组及之后派生的子组, 任务在p的常驻协程中运行, 不再每个任务创建一个协程
+func (g *Group) SetPool(p *pool.Pool) {
+	g.SetExecutor(p)
+}
+
+//本组及之后派生的子组, 任务panic时按policy处理, 子组可以再设置自己的policy
+func (g *Group) SetPanicPolicy(policy PanicPolicy) {
+	g.checkCallLogic()
+
+	g.m.Lock()
+	defer g.m.Unlock()
+
+	g.panicPolicy = policy
+}
+
+//本组及之后派生的子组, 任务panic时在任务的协程中调用f, 之后再按 PanicPolicy 处理
+func (g *Group) OnPanic(f func(e *TaskError)) {
+	g.checkCallLogic()
+
 	g.m.Lock()
 	defer g.m.Unlock()
 
-	g.max = n
+	g.onPanic = f
+}
+
+//本组及之后派生的子组, 按policy决定何时取消ctx并回滚, 默认 FailFast
+//不带上下文的组没有ctx可以取消, 也不回滚, 只收集错误
+func (g *Group) SetErrorPolicy(policy ErrorPolicy) {
+	g.checkCallLogic()
+
+	g.m.Lock()
+	defer g.m.Unlock()
+
+	g.errPolicy = policy
+}
+
+//本组同时运行的任务不超过n, n为0时不限制
+//可以在运行中调整: 调大立即放行等待中的 g.Go(), 调小则暂停放行直到运行数低于n
+func (g *Group) SetMaxGoroutine(n uint64) {
+	g.limiter.setSize(n)
 }
 
 func (g *Group) GetMaxGoroutine() uint64 {
+	return g.limiter.getSize()
+}
+
+//之后派生的整个子树都从本组的 MaxGoroutine 中取得预算, 子组仍可用 SetMaxGoroutine() 设置更小的本地上限
+func (g *Group) ShareMaxGoroutine() {
+	g.checkCallLogic()
+
 	g.m.Lock()
 	defer g.m.Unlock()
 
-	return g.max
+	g.isShared = true
 }
 
 func (g *Group) DiscardedContext() {
@@ -88,9 +252,25 @@ func (g *Group) DiscardedContext() {
 	return
 }
 
+//PanicRepanic 的组中有任务panic时, 整个派生树结束并回滚后, 在调用者的协程中重新panic
 func (g *Group) Wait(isParentRollback ...interface{}) []error {
+	err := g.wait(isParentRollback...)
+
+	if te := g.takePanic(); te != nil {
+		panic(te)
+	}
+	return err
+}
+
+//同 g.Wait(), 把整个派生树的错误合并为一个 *GroupError, 没有错误时返回nil
+func (g *Group) WaitErr() error {
+	return joinErrs(g.path, g.Wait())
+}
+
+func (g *Group) wait(isParentRollback ...interface{}) []error {
 	var err []error
 
+	g.m.Lock()
 	for _, b := range isParentRollback {
 		switch t := b.(type) {
 		case bool:
@@ -99,19 +279,33 @@ func (g *Group) Wait(isParentRollback ...interface{}) []error {
 			}
 		}
 	}
+	g.m.Unlock()
 
-	if g.wg.Wait(); g.cancel != nil {
-		g.cancel()
-	}
+	g.wg.Wait()
 
-	if (len(g.errs) > 0) && (g.ctx != nil) {
+	g.m.Lock()
+	if !g.isTripped && g.errPolicy.trip(g.failed(), g.done, true) {
+		g.isTripped = true
+	}
+	if g.isTripped && (g.ctx != nil) {
 		g.isRollback = true
 	}
+	isRollback := g.isRollback
+	//FailFast 或已触发错误策略时立即取消; 其他策略下子组的任务仍在使用本组的ctx, 等子组结束后再取消
+	late := g.errPolicy != FailFast && !g.isTripped
+	g.m.Unlock()
+
+	if !late && g.cancel != nil {
+		g.cancel()
+	}
 
 	for _, v := range g.child {
-		e := v.Wait(g.isRollback)
+		e := v.wait(isRollback)
 		err = append(err, e...)
 	}
+	if late && g.cancel != nil {
+		g.cancel()
+	}
 
 	if e := g.callRollback(); len(e) > 0 {
 		err = append(err, e...)
@@ -120,42 +314,192 @@ func (g *Group) Wait(isParentRollback ...interface{}) []error {
 	return append(err, g.errs...)
 }
 
-func (g *Group) Go(f interface{}, rollback ...interface{}) error {
-	g.m.Lock()
+//独立组和上下文组都可以使用, 上下文组中f不使用ctx; 任务的名字 回滚 超时 重试 优先级 权重见 Option
+func (g *Group) Go(f func() error, opts ...Option) error {
+	return g.goTask(g.funcTask(f, opts))
+}
+
+//独立组和上下文组都可以使用, 独立组中ctx为 context.Background(); 其余同 g.Go()
+func (g *Group) GoCtx(f func(ctx context.Context) error, opts ...Option) error {
+	return g.goTask(g.ctxTask(f, opts))
+}
+
+//同 g.Go(), 但不等待, 没有空闲位置时立即返回 ErrGroupFull
+func (g *Group) TryGo(f func() error, opts ...Option) error {
+	return g.tryGoTask(g.funcTask(f, opts))
+}
+
+//同 g.GoCtx(), 但不等待, 没有空闲位置时立即返回 ErrGroupFull
+func (g *Group) TryGoCtx(f func(ctx context.Context) error, opts ...Option) error {
+	return g.tryGoTask(g.ctxTask(f, opts))
+}
+
+//同 g.Go(), 返回的 Future 用于等待这一个任务, f的返回值通过 Future.Value() 读取
+func (g *Group) GoFuture(f func() (interface{}, error), opts ...Option) (*Future, error) {
+	fu := newFuture()
+	t := g.funcTask(func() (e error) {
+		fu.value, e = f()
+		return
+	}, append([]Option{withFn(f)}, opts...))
+	return g.goFuture(t, fu)
+}
+
+//同 g.GoCtx(), 其余同 g.GoFuture()
+func (g *Group) GoFutureCtx(f func(ctx context.Context) (interface{}, error), opts ...Option) (*Future, error) {
+	fu := newFuture()
+	t := g.ctxTask(func(ctx context.Context) (e error) {
+		fu.value, e = f(ctx)
+		return
+	}, append([]Option{withFn(f)}, opts...))
+	return g.goFuture(t, fu)
+}
+
+func (g *Group) goFuture(t *task, fu *Future) (*Future, error) {
+	t.future = fu
+	if e := g.goTask(t); e != nil {
+		return nil, e
+	}
+	return fu, nil
+}
+
+func (g *Group) funcTask(f func() error, opts []Option) *task {
+	t := newTask(f, opts)
+	if g.ctx == nil && t.timeout == 0 && t.retry == nil {
+		t.f = f
+		return t
+	}
+	return g.wrapCtx(t, func(context.Context) error { return f() })
+}
+
+func (g *Group) ctxTask(f func(ctx context.Context) error, opts []Option) *task {
+	return g.wrapCtx(newTask(f, opts), f)
+}
+
+//按本组的类型设置 t.f
+func (g *Group) wrapCtx(t *task, f func(ctx context.Context) error) *task {
+	//每次重试单独计时
+	if t.timeout > 0 {
+		f = timeoutFunc(t.timeout, t.isTransientTimeout, f)
+	}
+	if t.retry != nil {
+		f = t.retry.wrap(f)
+	}
+
+	if g.ctx != nil {
+		t.f = f
+	} else {
+		t.f = func() error { return f(context.Background()) }
+	}
+	return t
+}
+
+func (g *Group) goTask(t *task) error {
+	if g.limiter.tooHeavy(t.weight) {
+		return ErrWeightTooHeavy
+	}
+
+	ctx := t.waitCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return g.submit(ctx, t)
+}
+
+func (g *Group) tryGoTask(t *task) error {
+	if g.limiter.tooHeavy(t.weight) {
+		return ErrWeightTooHeavy
+	}
+	if !g.limiter.tryAcquire(t.weight) {
+		return ErrGroupFull
+	}
+
 	g.wg.Add(1)
-	c := g.counter
-	m := g.max
-	g.m.Unlock()
+	return g.start(t)
+}
+
+//Deprecated: 使用 g.Go() 或 g.GoCtx(), 回滚函数使用 WithRollback()
+//f 支持 .(func() error) 和 .(func(ctx context.Context) error), 类型不是 func() error 的回滚函数会被忽略
+func (g *Group) GoFunc(f interface{}, rollback ...interface{}) error {
+	return g.goFunc(f, rollback)
+}
+
+//Deprecated: 使用 g.Go() 或 g.GoCtx() 和 WithWaitContext()
+func (g *Group) GoContext(ctx context.Context, f interface{}, rollback ...interface{}) error {
+	return g.goFunc(f, rollback, WithWaitContext(ctx))
+}
+
+//Deprecated: 使用 g.Go() 或 g.GoCtx() 和 WithWeight()
+func (g *Group) GoWeighted(weight uint64, f interface{}, rollback ...interface{}) error {
+	return g.goFunc(f, rollback, WithWeight(weight))
+}
 
-	//todo:零时方案
-	if c >= m && m != 0 {
-		<-g.do
+//Deprecated: 使用 g.Go() 或 g.GoCtx() 和 WithPriority()
+func (g *Group) GoPriority(priority int, f interface{}, rollback ...interface{}) error {
+	return g.goFunc(f, rollback, WithPriority(priority))
+}
+
+//旧的 interface{} 入口, f的类型在占用 wg 和运行位置之前检查
+func (g *Group) goFunc(f interface{}, rollback []interface{}, opts ...Option) error {
+	for _, v := range rollback {
+		if r, ok := v.(func() error); ok {
+			opts = append(opts, WithRollback(r))
+		}
 	}
-	//for c >= m && m != 0 {
-	//	<-g.do
-	//
-	//	g.m.Lock()
-	//	c = g.counter
-	//	m = g.max
-	//	g.m.Unlock()
-	//}
 
+	switch v := f.(type) {
+	case func() error:
+		return g.Go(v, opts...)
+	case func(ctx context.Context) error:
+		return g.GoCtx(v, opts...)
+	}
+	return ErrFuncType
+}
+
+func (g *Group) submit(ctx context.Context, t *task) error {
+	g.wg.Add(1)
+	if e := g.limiter.acquire(ctx, t.weight, t.priority); e != nil {
+		g.wg.Done()
+		return e
+	}
+
+	return g.start(t)
+}
+
+//已取得运行位置
+func (g *Group) start(t *task) error {
 	g.m.Lock()
 	g.isUsed = true
 	g.total++
-	g.counter++
+	t.id = g.total
 	g.m.Unlock()
 
 	if g.ctx != nil {
-		if _, ok := f.(func(ctx context.Context) error); !ok {
-			return errors.New(GO_F_TYPE_ERR)
-		}
-		go g.fWithContext(f.(func(ctx context.Context) error), rollback...)
+		return g.execute(t, func() { g.fWithContext(t) })
+	}
+	return g.execute(t, func() { g.f(t) })
+}
+
+func (g *Group) execute(t *task, run func()) error {
+	//Executor接收后又丢弃了任务，本任务不会运行; 记录为暂时性错误, 不触发错误策略
+	drop := func(e error) {
+		defer g.wg.Done()
+		g.collectErrs(g.taskErr(t, KindDropped, time.Time{}, e))
+		g.counterUpdata(t.weight)
+		t.finish(e)
+	}
+
+	var e error
+	if pe, ok := g.executor.(PriorityExecutor); ok {
+		e = pe.ExecutePriority(t.priority, run, drop)
 	} else {
-		if _, ok := f.(func() error); !ok {
-			return errors.New(GO_F_TYPE_ERR)
-		}
-		go g.f(f.(func() error))
+		e = g.executor.Execute(run, drop)
+	}
+	//Executor拒绝了任务, 错误只返回给调用者, 不记录到 errs
+	if e != nil {
+		g.counterUpdata(t.weight)
+		t.finish(e)
+		g.wg.Done()
+		return e
 	}
 	return nil
 }
@@ -188,59 +532,106 @@ func (g *Group) GetErrs() []error {
 func (g *Group) Close() {
 	if (g.ctx != nil) && (g.cancel != nil) {
 		g.cancel()
+		g.m.Lock()
 		g.isRollback = true
+		g.m.Unlock()
 	}
 	return
 }
 
-//
-func (g *Group) counterUpdata() {
-	g.m.Lock()
-	defer g.m.Unlock()
-	g.counter--
-
-	//fixme:临时的方案
-	go func() { g.do <- true }()
-	//select {
-	//case g.do <- true:
-	//default:
-	//}
+//任务结束，让出位置给等待中的 g.Go()
+func (g *Group) counterUpdata(weight uint64) {
+	g.limiter.release(weight)
 }
 
-//func (g *Group) f(f func() error) {
-func (g *Group) f(f func() error) {
+func (g *Group) f(t *task) {
+	var err error
+	start := time.Now()
 	defer g.wg.Done()
+	defer func() { t.finish(err) }()
+	//先记录panic再让出位置, 等待中的任务能看到ctx已取消
+	defer g.counterUpdata(t.weight)
 	defer func() {
 		if e := recover(); e != nil {
-			g.collectErrs(errors.New(fmt.Sprint(e)))
+			err = pool.NewPanicError(e)
+			g.handlePanic(g.taskErr(t, KindPanic, start, err))
 		}
 	}()
-	defer g.counterUpdata()
 
-	if e := f(); e != nil {
-		g.collectErrs(e)
+	if err = t.f.(func() error)(); err != nil {
+		g.collectErrs(g.returnErr(t, start, err))
+	} else {
+		g.succeed()
 	}
 }
-func (g *Group) fWithContext(f func(ctx context.Context) error, rollback ...interface{}) {
+func (g *Group) fWithContext(t *task) {
+	var err error
+	start := time.Now()
 	defer g.wg.Done()
+	defer func() { t.finish(err) }()
 	defer func() {
-		for _, v := range rollback {
+		g.m.Lock()
+		defer g.m.Unlock()
+		for _, v := range t.rollback {
 			f, ok := v.(func() error)
 			if ok {
-				g.rollback <- f
+				g.rollbacks = append(g.rollbacks, rollbackFunc{f: f, t: t})
 			}
 		}
 	}()
+	defer g.counterUpdata(t.weight)
 	defer func() {
 		if e := recover(); e != nil {
-			g.collectErrs(errors.New(fmt.Sprint(e)))
+			err = pool.NewPanicError(e)
+			g.handlePanic(g.taskErr(t, KindPanic, start, err))
 		}
 	}()
 
-	if e := f(*g.ctx); e != nil {
-		g.collectErrs(e)
+	if err = t.f.(func(ctx context.Context) error)(*g.ctx); err != nil {
+		g.collectErrs(g.returnErr(t, start, err))
+	} else {
+		g.succeed()
+	}
+}
+
+func (g *Group) handlePanic(te *TaskError) {
+	g.collectErrs(te)
+	if g.onPanic != nil {
+		g.onPanic(te)
+	}
+
+	switch g.panicPolicy {
+	case PanicRepanic:
+		g.m.Lock()
+		if g.panicked == nil {
+			g.panicked = te
+		}
+		g.m.Unlock()
+	case PanicCrash:
+		fmt.Fprintf(os.Stderr, "%v\n%s", te, te.Err.(*PanicError).Stack)
+		//新协程中的panic没有人能recover
+		go panic(te)
+		select {}
+	}
+}
+
+//派生树中第一个待重新抛出的panic, 并清除全部
+func (g *Group) takePanic() *TaskError {
+	var first *TaskError
+	for _, v := range g.child {
+		if te := v.takePanic(); first == nil {
+			first = te
+		}
 	}
-	g.counterUpdata()
+
+	g.m.Lock()
+	defer g.m.Unlock()
+
+	if first == nil {
+		first = g.panicked
+	}
+	g.panicked = nil
+	return first
 }
 
 func (g *Group) checkCallLogic() {
@@ -253,32 +644,58 @@ func (g *Group) checkCallLogic() {
 	}
 }
 
+//任务出错结束, 暂时性错误只记录, 不计入 ErrorPolicy
 func (g *Group) collectErrs(err error) {
 	g.m.Lock()
 	defer g.m.Unlock()
 	g.errs = append(g.errs, err)
+	g.done++
+	if IsTransient(err) {
+		g.transient++
+		return
+	}
 
-	if g.cancel != nil {
-		g.cancel()
+	if !g.isTripped && g.errPolicy.trip(g.failed(), g.done, false) {
+		g.isTripped = true
+		if g.cancel != nil {
+			g.cancel()
+		}
 	}
 }
 
+//致命错误的数量. 调用时持有锁
+func (g *Group) failed() uint64 {
+	return uint64(len(g.errs)) - g.transient
+}
+
+//任务成功结束
+func (g *Group) succeed() {
+	g.m.Lock()
+	defer g.m.Unlock()
+
+	g.done++
+}
+
 //当并发线程某一个返回错误,或则panic时 执行回滚
 //父亲组产生回滚,子组树全部产生回滚,不带上下文的节点及派生的子树不回滚
 //子组产生回滚，其父不回滚
 func (g *Group) callRollback() []error {
 	var err []error
-	if g.isRollback {
-		for {
-			select {
-			case f := <-g.rollback:
-				if e := f(); e != nil {
-					err = append(err, errors.New(fmt.Sprintln(ROLLBACK_ERR, e)))
-				}
-				continue
-			default:
+
+	g.m.Lock()
+	isRollback := g.isRollback
+	rollbacks := g.rollbacks
+	if isRollback {
+		g.rollbacks = nil
+	}
+	g.m.Unlock()
+
+	if isRollback {
+		for _, r := range rollbacks {
+			start := time.Now()
+			if e := r.f(); e != nil {
+				err = append(err, g.taskErr(r.t, KindRollback, start, e))
 			}
-			break
 		}
 	}
 	return err
@@ -3,7 +3,10 @@ package group
 import (
 	"context"
 	"errors"
+	"github.com/XeiTongXueFlyMe/poolgroup/pool"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"os/exec"
 	"sync"
 	"testing"
 	"time"
@@ -204,16 +207,16 @@ func TestGroupWait(t *testing.T) {
 
 	B := g.ForkChild()
 	B.WithContext(context.TODO())
-	assert.NoError(t, B.Go(f.funcCtxA))
-	assert.NoError(t, B.Go(f.funcCtxC))
-	assert.NoError(t, B.Go(f.funcCtxC))
-	assert.NoError(t, B.Go(f.funcTimeOut))
+	assert.NoError(t, B.GoCtx(f.funcCtxA))
+	assert.NoError(t, B.GoCtx(f.funcCtxC))
+	assert.NoError(t, B.GoCtx(f.funcCtxC))
+	assert.NoError(t, B.GoCtx(f.funcTimeOut))
 
 	a := B.ForkChild()
-	assert.NoError(t, a.Go(f.funcCtxA))
-	assert.NoError(t, a.Go(f.funcCtxC))
-	assert.NoError(t, a.Go(f.funcCtxC))
-	assert.NoError(t, a.Go(f.funcTimeOut))
+	assert.NoError(t, a.GoCtx(f.funcCtxA))
+	assert.NoError(t, a.GoCtx(f.funcCtxC))
+	assert.NoError(t, a.GoCtx(f.funcCtxC))
+	assert.NoError(t, a.GoCtx(f.funcTimeOut))
 
 	b := A.ForkChild()
 	assert.NoError(t, b.Go(f.funcB))
@@ -230,8 +233,8 @@ func TestGroupDiscardedContext(t *testing.T) {
 
 	g := NewGroup()
 	g.WithContext(context.Background())
-	assert.NoError(t, g.Go(f.funcCtxA))
-	assert.NoError(t, g.Go(f.funcTimeOut))
+	assert.NoError(t, g.GoCtx(f.funcCtxA))
+	assert.NoError(t, g.GoCtx(f.funcTimeOut))
 
 	A := g.ForkChild()
 	A.DiscardedContext()
@@ -248,7 +251,7 @@ func TestGroupTimeout(t *testing.T) {
 
 	g := NewGroup()
 	g.WithTimeout(context.Background(), 100*time.Millisecond)
-	assert.NoError(t, g.Go(f.funcCtxA))
+	assert.NoError(t, g.GoCtx(f.funcCtxA))
 
 	A := g.ForkChild()
 	A.DiscardedContext()
@@ -265,11 +268,11 @@ func TestGroupClose(t *testing.T) {
 
 	g := NewGroup()
 	g.WithContext(context.TODO())
-	assert.NoError(t, g.Go(f.funcCtxA))
+	assert.NoError(t, g.GoCtx(f.funcCtxA))
 
 	A := g.ForkChild()
-	assert.NoError(t, A.Go(f.funcCtxA))
-	assert.NoError(t, A.Go(f.funcCtxB))
+	assert.NoError(t, A.GoCtx(f.funcCtxA))
+	assert.NoError(t, A.GoCtx(f.funcCtxB))
 
 	a := A.ForkChild()
 	a.DiscardedContext()
@@ -296,16 +299,16 @@ func TestGroupGoroutineNum(t *testing.T) {
 
 	B := g.ForkChild()
 	B.WithContext(context.TODO())
-	assert.NoError(t, B.Go(f.funcCtxA))
-	assert.NoError(t, B.Go(f.funcCtxC))
-	assert.NoError(t, B.Go(f.funcCtxC))
-	assert.NoError(t, B.Go(f.funcTimeOut))
+	assert.NoError(t, B.GoCtx(f.funcCtxA))
+	assert.NoError(t, B.GoCtx(f.funcCtxC))
+	assert.NoError(t, B.GoCtx(f.funcCtxC))
+	assert.NoError(t, B.GoCtx(f.funcTimeOut))
 
 	a := B.ForkChild()
-	assert.NoError(t, a.Go(f.funcCtxA))
-	assert.NoError(t, a.Go(f.funcCtxC))
-	assert.NoError(t, a.Go(f.funcCtxC))
-	assert.NoError(t, a.Go(f.funcTimeOut))
+	assert.NoError(t, a.GoCtx(f.funcCtxA))
+	assert.NoError(t, a.GoCtx(f.funcCtxC))
+	assert.NoError(t, a.GoCtx(f.funcCtxC))
+	assert.NoError(t, a.GoCtx(f.funcTimeOut))
 
 	b := A.ForkChild()
 	assert.NoError(t, b.Go(f.funcB))
@@ -320,7 +323,6 @@ func TestGroupGoroutineNum(t *testing.T) {
 }
 
 func TestGroupGetErrs(t *testing.T) {
-	err := []error{errors.New("time out"), errors.New("time out")}
 	f := class{}
 
 	g := NewGroup()
@@ -334,23 +336,34 @@ func TestGroupGetErrs(t *testing.T) {
 
 	B := g.ForkChild()
 	B.WithContext(context.TODO())
-	assert.NoError(t, B.Go(f.funcCtxA))
-	assert.NoError(t, B.Go(f.funcCtxC))
-	assert.NoError(t, B.Go(f.funcCtxC))
-	assert.NoError(t, B.Go(f.funcTimeOut))
+	assert.NoError(t, B.GoCtx(f.funcCtxA))
+	assert.NoError(t, B.GoCtx(f.funcCtxC))
+	assert.NoError(t, B.GoCtx(f.funcCtxC))
+	assert.NoError(t, B.GoCtx(f.funcTimeOut))
 
 	a := B.ForkChild()
-	assert.NoError(t, a.Go(f.funcCtxA))
-	assert.NoError(t, a.Go(f.funcCtxC))
-	assert.NoError(t, a.Go(f.funcCtxC))
-	assert.NoError(t, a.Go(f.funcTimeOut))
+	assert.NoError(t, a.GoCtx(f.funcCtxA))
+	assert.NoError(t, a.GoCtx(f.funcCtxC))
+	assert.NoError(t, a.GoCtx(f.funcCtxC))
+	assert.NoError(t, a.GoCtx(f.funcTimeOut))
 
 	b := A.ForkChild()
 	assert.NoError(t, b.Go(f.funcB))
 	assert.NoError(t, b.Go(f.funcC))
 
 	g.Wait()
-	assert.EqualValues(t, g.GetErrs(), err)
+	errs := g.GetErrs()
+	assert.Len(t, errs, 2)
+	//先子组后本组
+	for i, path := range []string{"root/1/0", "root/1"} {
+		var te *TaskError
+		assert.True(t, errors.As(errs[i], &te))
+		assert.EqualError(t, te.Err, "time out")
+		assert.Equal(t, KindReturn, te.Kind)
+		assert.Equal(t, path, te.Path)
+		assert.EqualValues(t, 4, te.ID)
+		assert.Contains(t, te.Name, "funcTimeOut")
+	}
 }
 
 func TestGroupRollback_0(t *testing.T) {
@@ -358,11 +371,11 @@ func TestGroupRollback_0(t *testing.T) {
 
 	g := NewGroup()
 	g.WithContext(context.TODO())
-	assert.NoError(t, g.Go(f.funcCtxA))
-	assert.NoError(t, g.Go(f.funcCtxA, f.funcResetA))
-	assert.NoError(t, g.Go(f.funcCtxB))
-	assert.NoError(t, g.Go(f.funcCtxC, f.funcResetC))
-	assert.NoError(t, g.Go(f.funcTimeOut))
+	assert.NoError(t, g.GoCtx(f.funcCtxA))
+	assert.NoError(t, g.GoCtx(f.funcCtxA, WithRollback(f.funcResetA)))
+	assert.NoError(t, g.GoCtx(f.funcCtxB))
+	assert.NoError(t, g.GoCtx(f.funcCtxC, WithRollback(f.funcResetC)))
+	assert.NoError(t, g.GoCtx(f.funcTimeOut))
 
 	g.Wait()
 	assert.EqualValues(t, 0, f.a)
@@ -374,16 +387,16 @@ func TestGroupRollback_1(t *testing.T) {
 
 	g := NewGroup()
 	g.WithContext(context.TODO())
-	assert.NoError(t, g.Go(f.funcCtxA))
-	assert.NoError(t, g.Go(f.funcCtxA))
-	assert.NoError(t, g.Go(f.funcCtxB))
-	assert.NoError(t, g.Go(f.funcCtxC))
+	assert.NoError(t, g.GoCtx(f.funcCtxA))
+	assert.NoError(t, g.GoCtx(f.funcCtxA))
+	assert.NoError(t, g.GoCtx(f.funcCtxB))
+	assert.NoError(t, g.GoCtx(f.funcCtxC))
 
 	a := g.ForkChild()
-	assert.NoError(t, a.Go(f.funcCtxA))
-	assert.NoError(t, a.Go(f.funcCtxA, f.funcResetA))
-	assert.NoError(t, a.Go(f.funcCtxB))
-	assert.NoError(t, a.Go(f.funcCtxC, f.funcResetC))
+	assert.NoError(t, a.GoCtx(f.funcCtxA))
+	assert.NoError(t, a.GoCtx(f.funcCtxA, WithRollback(f.funcResetA)))
+	assert.NoError(t, a.GoCtx(f.funcCtxB))
+	assert.NoError(t, a.GoCtx(f.funcCtxC, WithRollback(f.funcResetC)))
 
 	a.Close()
 	time.AfterFunc(time.Millisecond*300, g.Close)
@@ -398,27 +411,27 @@ func TestGroupRollback_2(t *testing.T) {
 
 	g := NewGroup()
 	g.WithContext(context.TODO())
-	assert.NoError(t, g.Go(f.funcCtxA))
-	assert.NoError(t, g.Go(f.funcCtxA))
-	assert.NoError(t, g.Go(f.funcCtxB))
-	assert.NoError(t, g.Go(f.funcCtxC))
-	assert.NoError(t, g.Go(f.funcTimeOut))
+	assert.NoError(t, g.GoCtx(f.funcCtxA))
+	assert.NoError(t, g.GoCtx(f.funcCtxA))
+	assert.NoError(t, g.GoCtx(f.funcCtxB))
+	assert.NoError(t, g.GoCtx(f.funcCtxC))
+	assert.NoError(t, g.GoCtx(f.funcTimeOut))
 
 	A := g.ForkChild()
-	assert.NoError(t, A.Go(f.funcCtxA))
-	assert.NoError(t, A.Go(f.funcCtxA))
-	assert.NoError(t, A.Go(f.funcCtxB))
-	assert.NoError(t, A.Go(f.funcCtxC))
+	assert.NoError(t, A.GoCtx(f.funcCtxA))
+	assert.NoError(t, A.GoCtx(f.funcCtxA))
+	assert.NoError(t, A.GoCtx(f.funcCtxB))
+	assert.NoError(t, A.GoCtx(f.funcCtxC))
 
 	a := A.ForkChild()
-	assert.NoError(t, a.Go(f.funcCtxA))
-	assert.NoError(t, a.Go(f.funcCtxA, f.funcResetA))
-	assert.NoError(t, a.Go(f.funcCtxC))
+	assert.NoError(t, a.GoCtx(f.funcCtxA))
+	assert.NoError(t, a.GoCtx(f.funcCtxA, WithRollback(f.funcResetA)))
+	assert.NoError(t, a.GoCtx(f.funcCtxC))
 
 	aa := a.ForkChild()
-	assert.NoError(t, aa.Go(f.funcCtxA))
-	assert.NoError(t, aa.Go(f.funcCtxA))
-	assert.NoError(t, aa.Go(f.funcCtxC, f.funcResetC))
+	assert.NoError(t, aa.GoCtx(f.funcCtxA))
+	assert.NoError(t, aa.GoCtx(f.funcCtxA))
+	assert.NoError(t, aa.GoCtx(f.funcCtxC, WithRollback(f.funcResetC)))
 
 	g.Wait()
 	assert.EqualValues(t, 0, f.a)
@@ -431,30 +444,30 @@ func TestGroupRollback_3(t *testing.T) {
 
 	g := NewGroup()
 	g.WithContext(context.TODO())
-	assert.NoError(t, g.Go(f.funcCtxA))
-	assert.NoError(t, g.Go(f.funcCtxA))
-	assert.NoError(t, g.Go(f.funcCtxB, f.funcResetB))
-	assert.NoError(t, g.Go(f.funcCtxC))
-	assert.NoError(t, g.Go(func(ctx context.Context) error {
+	assert.NoError(t, g.GoCtx(f.funcCtxA))
+	assert.NoError(t, g.GoCtx(f.funcCtxA))
+	assert.NoError(t, g.GoCtx(f.funcCtxB, WithRollback(f.funcResetB)))
+	assert.NoError(t, g.GoCtx(f.funcCtxC))
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error {
 		return f.funcComplete(ctx, g.cancel)
 	}))
 
 	A := g.ForkChild()
-	assert.NoError(t, A.Go(f.funcCtxA))
-	assert.NoError(t, A.Go(f.funcCtxA))
-	assert.NoError(t, A.Go(f.funcCtxB, f.funcResetB))
-	assert.NoError(t, A.Go(f.funcCtxC))
+	assert.NoError(t, A.GoCtx(f.funcCtxA))
+	assert.NoError(t, A.GoCtx(f.funcCtxA))
+	assert.NoError(t, A.GoCtx(f.funcCtxB, WithRollback(f.funcResetB)))
+	assert.NoError(t, A.GoCtx(f.funcCtxC))
 
 	a := A.ForkChild()
-	assert.NoError(t, a.Go(f.funcCtxA))
-	assert.NoError(t, a.Go(f.funcCtxA))
-	assert.NoError(t, a.Go(f.funcCtxC, f.funcResetC))
-	assert.NoError(t, a.Go(f.funcTimeOut))
+	assert.NoError(t, a.GoCtx(f.funcCtxA))
+	assert.NoError(t, a.GoCtx(f.funcCtxA))
+	assert.NoError(t, a.GoCtx(f.funcCtxC, WithRollback(f.funcResetC)))
+	assert.NoError(t, a.GoCtx(f.funcTimeOut))
 
 	aa := a.ForkChild()
-	assert.NoError(t, aa.Go(f.funcCtxA))
-	assert.NoError(t, aa.Go(f.funcCtxA, f.funcResetA))
-	assert.NoError(t, aa.Go(f.funcCtxC))
+	assert.NoError(t, aa.GoCtx(f.funcCtxA))
+	assert.NoError(t, aa.GoCtx(f.funcCtxA, WithRollback(f.funcResetA)))
+	assert.NoError(t, aa.GoCtx(f.funcCtxC))
 
 	<-time.After(time.Millisecond * 300)
 	g.Wait()
@@ -470,10 +483,10 @@ func TestGroupRollback_4(t *testing.T) {
 
 	g := NewGroup()
 	g.WithContext(context.TODO())
-	assert.NoError(t, g.Go(f.funcCtxA))
-	assert.NoError(t, g.Go(f.funcCtxB))
-	assert.NoError(t, g.Go(f.funcCtxC))
-	assert.NoError(t, g.Go(f.funcTimeOut))
+	assert.NoError(t, g.GoCtx(f.funcCtxA))
+	assert.NoError(t, g.GoCtx(f.funcCtxB))
+	assert.NoError(t, g.GoCtx(f.funcCtxC))
+	assert.NoError(t, g.GoCtx(f.funcTimeOut))
 
 	A := g.ForkChild()
 	A.DiscardedContext()
@@ -483,9 +496,9 @@ func TestGroupRollback_4(t *testing.T) {
 
 	a := A.ForkChild()
 	a.WithContext(context.TODO())
-	assert.NoError(t, a.Go(f.funcCtxA))
-	assert.NoError(t, a.Go(f.funcCtxA, f.funcResetA))
-	assert.NoError(t, a.Go(f.funcCtxC, f.funcResetC))
+	assert.NoError(t, a.GoCtx(f.funcCtxA))
+	assert.NoError(t, a.GoCtx(f.funcCtxA, WithRollback(f.funcResetA)))
+	assert.NoError(t, a.GoCtx(f.funcCtxC, WithRollback(f.funcResetC)))
 
 	time.AfterFunc(time.Millisecond*250, a.Close)
 	g.Wait()
@@ -526,3 +539,452 @@ func TestGroupMax(t *testing.T) {
 	assert.EqualValues(t, uint64(1000), g.GetGoroutineNum())
 	assert.EqualValues(t, uint64(1000), count)
 }
+
+func TestGroupPool(t *testing.T) {
+	f := class{}
+	p := pool.NewPool(16)
+	defer p.Stop()
+
+	g := NewGroup()
+	g.SetPool(p)
+	g.WithContext(context.TODO())
+	assert.NoError(t, g.GoCtx(f.funcCtxA, WithRollback(f.funcResetA)))
+	assert.NoError(t, g.GoCtx(f.funcCtxB))
+	assert.NoError(t, g.GoCtx(f.funcTimeOut))
+
+	A := g.ForkChild()
+	A.DiscardedContext()
+	assert.NoError(t, A.Go(f.funcB))
+	assert.NoError(t, A.Go(f.funcC))
+
+	a := A.ForkChild()
+	assert.NoError(t, a.Go(f.funcC))
+	assert.NoError(t, a.Go(func() error { panic("panic") }))
+
+	errs := g.Wait()
+	assert.Empty(t, p.Wait())
+
+	assert.Len(t, errs, 2)
+	assert.EqualValues(t, 7, g.GetGoroutineNum())
+	assert.EqualValues(t, 0, f.a)
+	assert.EqualValues(t, 2, f.b)
+	assert.EqualValues(t, 2, f.c)
+}
+
+func TestGroupPoolStopped(t *testing.T) {
+	p := pool.NewPool(1)
+	p.Stop()
+
+	g := NewGroup()
+	g.SetPool(p)
+	assert.EqualError(t, g.Go(func() error { return nil }), pool.POOL_STOPPED_ERR)
+	assert.Len(t, g.Wait(), 0)
+}
+
+func TestGroupWithWeight(t *testing.T) {
+	var running, peak int64
+	m := sync.Mutex{}
+	task := func(weight int64) func() error {
+		return func() error {
+			m.Lock()
+			running += weight
+			if running > peak {
+				peak = running
+			}
+			m.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			m.Lock()
+			running -= weight
+			m.Unlock()
+			return nil
+		}
+	}
+
+	g := NewGroup()
+	g.SetMaxGoroutine(10)
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, g.Go(task(6), WithWeight(6)))
+		assert.NoError(t, g.Go(task(1)))
+	}
+	assert.Equal(t, ErrWeightTooHeavy, g.Go(task(11), WithWeight(11)))
+	g.Wait()
+
+	assert.True(t, peak <= 10)
+	assert.EqualValues(t, 40, g.GetGoroutineNum())
+
+	//weight为0按1计算, 不绕过预算
+	running, peak = 0, 0
+	g = NewGroup()
+	g.SetMaxGoroutine(1)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, g.Go(task(1), WithWeight(0)))
+	}
+	g.Wait()
+	assert.EqualValues(t, 1, peak)
+}
+
+func TestGroupShareMaxGoroutine(t *testing.T) {
+	var running, peak, runningA, peakA int64
+	m := sync.Mutex{}
+	task := func(isA bool) func() error {
+		return func() error {
+			m.Lock()
+			running++
+			if running > peak {
+				peak = running
+			}
+			if isA {
+				runningA++
+				if runningA > peakA {
+					peakA = runningA
+				}
+			}
+			m.Unlock()
+			time.Sleep(2 * time.Millisecond)
+			m.Lock()
+			running--
+			if isA {
+				runningA--
+			}
+			m.Unlock()
+			return nil
+		}
+	}
+
+	g := NewGroup()
+	g.SetMaxGoroutine(4)
+	g.ShareMaxGoroutine()
+
+	A := g.ForkChild()
+	A.SetMaxGoroutine(1)
+	B := g.ForkChild()
+	b := B.ForkChild()
+
+	wg := sync.WaitGroup{}
+	for _, v := range []*Group{g, A, B, b} {
+		wg.Add(1)
+		go func(c *Group) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				assert.NoError(t, c.Go(task(c == A)))
+			}
+		}(v)
+	}
+	wg.Wait()
+	g.Wait()
+
+	assert.True(t, peak <= 4)
+	assert.EqualValues(t, 1, peakA)
+	assert.EqualValues(t, 80, g.GetGoroutineNum())
+	assert.Equal(t, ErrWeightTooHeavy, b.Go(task(false), WithWeight(5)))
+}
+
+func TestGroupWithWaitContext(t *testing.T) {
+	block := make(chan struct{})
+
+	g := NewGroup()
+	g.SetMaxGoroutine(1)
+	assert.NoError(t, g.Go(func() error { <-block; return nil }, WithWaitContext(context.TODO())))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, g.Go(func() error { return nil }, WithWaitContext(ctx)))
+
+	//ctx已经结束, 有空闲位置也不提交
+	close(block)
+	g.Wait()
+	assert.Equal(t, context.DeadlineExceeded, g.GoCtx(func(ctx context.Context) error { return nil }, WithWaitContext(ctx)))
+	assert.NoError(t, g.Go(func() error { return nil }, WithWaitContext(context.TODO())))
+	g.Wait()
+	assert.EqualValues(t, 2, g.GetGoroutineNum())
+}
+
+func TestGroupTryGo(t *testing.T) {
+	block := make(chan struct{})
+
+	g := NewGroup()
+	g.SetMaxGoroutine(2)
+	assert.NoError(t, g.TryGo(func() error { <-block; return nil }))
+	assert.NoError(t, g.TryGo(func() error { <-block; return nil }))
+	assert.Equal(t, ErrGroupFull, g.TryGo(func() error { return nil }))
+
+	close(block)
+	g.Wait()
+	assert.NoError(t, g.TryGo(func() error { return nil }))
+	g.Wait()
+	assert.EqualValues(t, 3, g.GetGoroutineNum())
+
+	//选项和 g.Go() 相同
+	f := class{a: 1}
+	g = NewGroup()
+	g.WithContext(context.TODO())
+	g.SetMaxGoroutine(2)
+	assert.Equal(t, ErrWeightTooHeavy, g.TryGo(func() error { return nil }, WithWeight(3)))
+	assert.NoError(t, g.TryGo(func() error { return nil }, WithRollback(f.funcResetA)))
+	assert.NoError(t, g.TryGoCtx(func(ctx context.Context) error { return errTest }, WithName("fail")))
+	errs := g.Wait()
+	assert.Len(t, errs, 1)
+	var te *TaskError
+	assert.True(t, errors.As(errs[0], &te))
+	assert.Equal(t, "fail", te.Name)
+	assert.Equal(t, 0, f.a)
+}
+
+//f类型错误时不占用 wg 和运行位置, g.Wait() 不会阻塞
+func TestGroupFuncType(t *testing.T) {
+	wait := func(g *Group) []error {
+		done := make(chan []error)
+		go func() { done <- g.Wait() }()
+		select {
+		case errs := <-done:
+			return errs
+		case <-time.After(time.Second):
+			t.Fatal("g.Wait() blocked")
+			return nil
+		}
+	}
+	bad := func(ctx context.Context) {}
+
+	ctxGroup := NewGroup()
+	ctxGroup.WithContext(context.TODO())
+
+	//独立组和上下文组
+	for _, g := range []*Group{NewGroup(), ctxGroup} {
+		g.SetMaxGoroutine(1)
+		assert.Equal(t, ErrFuncType, g.GoFunc(bad))
+		assert.Equal(t, ErrFuncType, g.GoContext(context.TODO(), bad))
+		assert.Equal(t, ErrFuncType, g.GoWeighted(1, bad))
+		assert.Equal(t, ErrFuncType, g.GoPriority(1, bad))
+		assert.Equal(t, ErrFuncType, g.GoRetry(Retry{}, bad))
+		assert.Equal(t, ErrFuncType, g.GoTimeout(time.Second, bad))
+		assert.EqualValues(t, 0, g.GetGoroutineNum())
+		assert.Len(t, wait(g), 0)
+
+		//运行位置没有泄漏, 两种f在两种组中都可以使用
+		assert.NoError(t, g.TryGo(func() error { return nil }))
+		assert.Len(t, wait(g), 0)
+		assert.NoError(t, g.TryGoCtx(func(ctx context.Context) error { return nil }))
+		assert.Len(t, wait(g), 0)
+		assert.EqualValues(t, 2, g.GetGoroutineNum())
+	}
+}
+
+func TestGroupWithPriority(t *testing.T) {
+	var order []int
+	m := sync.Mutex{}
+	block := make(chan struct{})
+
+	g := NewGroup()
+	g.SetMaxGoroutine(1)
+	assert.NoError(t, g.Go(func() error { <-block; return nil }))
+
+	for i, priority := range []int{0, 10, 5} {
+		go func(i, priority int) {
+			assert.NoError(t, g.Go(func() error {
+				m.Lock()
+				defer m.Unlock()
+				order = append(order, i)
+				return nil
+			}, WithPriority(priority)))
+		}(i, priority)
+		waitQueued(&g.limiter, i+1)
+	}
+	close(block)
+	for g.GetGoroutineNum() != 4 {
+		time.Sleep(time.Millisecond)
+	}
+	g.Wait()
+
+	assert.Equal(t, []int{1, 2, 0}, order)
+}
+
+func TestGroupPanicRepanic(t *testing.T) {
+	f := class{a: 1}
+
+	g := NewGroup()
+	g.SetPanicPolicy(PanicRepanic)
+	child := g.ForkChild()
+	child.WithContext(context.TODO())
+	assert.NoError(t, child.GoCtx(f.funcCtxA, WithRollback(f.funcResetA)))
+	assert.NoError(t, child.GoCtx(func(ctx context.Context) error { panic("panic") }))
+	assert.NoError(t, g.Go(func() error { return nil }))
+
+	var r interface{}
+	func() {
+		defer func() { r = recover() }()
+		g.Wait()
+	}()
+
+	te, ok := r.(*TaskError)
+	assert.True(t, ok)
+	assert.Equal(t, KindPanic, te.Kind)
+	assert.Equal(t, "root/0", te.Path)
+	assert.EqualError(t, te.Err, "panic")
+	//重新panic之前已经回滚
+	assert.Equal(t, 0, f.a)
+
+	//已经抛出过, 不再panic
+	assert.Len(t, g.Wait(), 1)
+}
+
+func TestGroupOnPanic(t *testing.T) {
+	var m sync.Mutex
+	var panics []*TaskError
+
+	g := NewGroup()
+	g.OnPanic(func(e *TaskError) {
+		m.Lock()
+		defer m.Unlock()
+		panics = append(panics, e)
+	})
+	assert.NoError(t, g.ForkChild().Go(func() error { panic("child") }))
+	assert.NoError(t, g.Go(func() error { return errors.New("err") }))
+
+	//默认 PanicRecover, 不会重新panic
+	assert.Len(t, g.Wait(), 2)
+	assert.Len(t, panics, 1)
+	assert.Equal(t, "root/0", panics[0].Path)
+	assert.Equal(t, "child", panics[0].Err.(*PanicError).Value)
+}
+
+func crashTask() error {
+	panic("crash")
+}
+
+func TestGroupPanicCrash(t *testing.T) {
+	if os.Getenv("GROUP_PANIC_CRASH") == "1" {
+		p := pool.NewPool(1)
+		g := NewGroup()
+		g.SetPool(p)
+		g.SetPanicPolicy(PanicCrash)
+		g.Go(crashTask)
+		g.Wait()
+		return
+	}
+
+	//在子进程中运行, 即使任务在pool中运行, 进程也会崩溃
+	cmd := exec.Command(os.Args[0], "-test.run=^TestGroupPanicCrash$")
+	cmd.Env = append(os.Environ(), "GROUP_PANIC_CRASH=1")
+	out, err := cmd.CombinedOutput()
+
+	_, ok := err.(*exec.ExitError)
+	assert.True(t, ok)
+	assert.Contains(t, string(out), "crashTask")
+}
+
+//按顺序同步运行 results, 返回ctx是否被取消和回滚的次数
+func runErrorPolicy(policy ErrorPolicy, results ...bool) (cancelled []bool, rollback int) {
+	g := NewGroup()
+	g.SetExecutor(SyncExecutor)
+	g.SetErrorPolicy(policy)
+	c := g.WithContext(context.TODO())
+
+	for _, ok := range results {
+		ok := ok
+		g.GoCtx(func(ctx context.Context) error {
+			if ok {
+				return nil
+			}
+			return errors.New("err")
+		}, WithRollback(func() error {
+			rollback++
+			return nil
+		}))
+		cancelled = append(cancelled, c.Err() != nil)
+	}
+	g.Wait()
+	return
+}
+
+func TestGroupErrorPolicy(t *testing.T) {
+	cancelled, rollback := runErrorPolicy(FailFast, true, false, true)
+	assert.Equal(t, []bool{false, true, true}, cancelled)
+	assert.Equal(t, 3, rollback)
+
+	cancelled, rollback = runErrorPolicy(CollectAll, false, false, true)
+	assert.Equal(t, []bool{false, false, false}, cancelled)
+	assert.Equal(t, 0, rollback)
+
+	cancelled, rollback = runErrorPolicy(CancelAfter(2), false, true, false, true)
+	assert.Equal(t, []bool{false, false, true, true}, cancelled)
+	assert.Equal(t, 4, rollback)
+
+	cancelled, rollback = runErrorPolicy(CancelAfter(2), false, true, true)
+	assert.Equal(t, []bool{false, false, false}, cancelled)
+	assert.Equal(t, 0, rollback)
+}
+
+func TestGroupErrorRatio(t *testing.T) {
+	//前4个任务结束前不判断, 之后比例超过0.5取消
+	cancelled, rollback := runErrorPolicy(CancelAboveRatio(0.5, 4), false, false, true, true, false, true)
+	assert.Equal(t, []bool{false, false, false, false, true, true}, cancelled)
+	assert.Equal(t, 6, rollback)
+
+	cancelled, rollback = runErrorPolicy(CancelAboveRatio(0.5, 4), false, true, true, true, false, true)
+	assert.Equal(t, []bool{false, false, false, false, false, false}, cancelled)
+	assert.Equal(t, 0, rollback)
+
+	//没有达到min, g.Wait() 时按全部任务的比例回滚
+	cancelled, rollback = runErrorPolicy(CancelAboveRatio(0.3, 10), false, true)
+	assert.Equal(t, []bool{false, false}, cancelled)
+	assert.Equal(t, 2, rollback)
+}
+
+func TestGroupErrorPolicyFork(t *testing.T) {
+	g := NewGroup()
+	g.WithContext(context.TODO())
+	g.SetErrorPolicy(CollectAll)
+	child := g.ForkChild()
+	assert.NoError(t, child.GoCtx(func(ctx context.Context) error { return errors.New("err") }))
+	assert.NoError(t, child.GoCtx(func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(20 * time.Millisecond):
+			return nil
+		}
+	}))
+	//子组继承 CollectAll, 不取消兄弟任务
+	assert.Len(t, g.Wait(), 1)
+}
+
+//本组的任务结束后, FailFast 或已触发的错误策略取消本组的ctx, 子组中仍在运行的任务收到取消; 没有触发时等子组结束后再取消
+func TestGroupWaitCancelChild(t *testing.T) {
+	for _, c := range []struct {
+		policy   ErrorPolicy
+		errs     int
+		canceled bool
+	}{
+		{FailFast, 0, true},
+		{CollectAll, 1, false},
+		{CancelAfter(2), 1, false},
+		{CancelAfter(2), 2, true},
+		{CancelAboveRatio(0.5, 3), 1, false},
+		{CancelAboveRatio(0.5, 3), 2, true},
+	} {
+		var ctxErr error
+		g := NewGroup()
+		g.WithContext(context.TODO())
+		g.SetErrorPolicy(c.policy)
+		assert.NoError(t, g.Go(func() error { return nil }))
+		for i := 0; i < c.errs; i++ {
+			assert.NoError(t, g.Go(func() error { return errTest }))
+		}
+		child := g.ForkChild()
+		assert.NoError(t, child.GoCtx(func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				ctxErr = ctx.Err()
+			case <-time.After(50 * time.Millisecond):
+			}
+			return nil
+		}))
+		assert.Len(t, g.Wait(), c.errs)
+
+		if c.canceled {
+			assert.Equal(t, context.Canceled, ctxErr, c)
+		} else {
+			assert.NoError(t, ctxErr, c)
+		}
+	}
+}
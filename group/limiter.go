@@ -0,0 +1,184 @@
+package group
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+//带权重的信号量, 运行中任务的权重之和不超过size, size为0时不限制
+//等待者按优先级从高到低唤醒, 相同优先级按FIFO顺序
+//parent不为空时, 还要从parent取得同样的权重, 子树的总量受祖先的size限制
+type limiter struct {
+	m       sync.Mutex
+	size    uint64
+	cur     uint64
+	waiters list.List
+	parent  *limiter
+}
+
+type waiter struct {
+	n        uint64
+	priority int
+	ready    chan struct{}
+}
+
+//ctx 结束前没有取得时返回 ctx.Err(), 已取得的祖先或本地部分会退还
+func (l *limiter) acquire(ctx context.Context, n uint64, priority int) error {
+	if e := l.acquireLocal(ctx, n, priority); e != nil {
+		return e
+	}
+
+	//先本地后祖先, 顺序固定不会死锁
+	if l.parent != nil {
+		if e := l.parent.acquire(ctx, n, priority); e != nil {
+			l.releaseLocal(n)
+			return e
+		}
+	}
+	return nil
+}
+
+//不等待, 本地或某个祖先取不到时返回false
+func (l *limiter) tryAcquire(n uint64) bool {
+	l.m.Lock()
+	if l.waiters.Len() != 0 || !l.fits(n) {
+		l.m.Unlock()
+		return false
+	}
+	l.cur += n
+	l.m.Unlock()
+
+	if l.parent != nil && !l.parent.tryAcquire(n) {
+		l.releaseLocal(n)
+		return false
+	}
+	return true
+}
+
+func (l *limiter) release(n uint64) {
+	l.releaseLocal(n)
+
+	if l.parent != nil {
+		l.parent.release(n)
+	}
+}
+
+//n 超过本身或某个祖先的size, 按当前size永远无法取得
+func (l *limiter) tooHeavy(n uint64) bool {
+	for p := l; p != nil; p = p.parent {
+		if size := p.getSize(); size != 0 && n > size {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *limiter) acquireLocal(ctx context.Context, n uint64, priority int) error {
+	//ctx已经结束, 有空闲位置也不取得
+	if e := ctx.Err(); e != nil {
+		return e
+	}
+
+	l.m.Lock()
+	//有人在排队时不能插队, 即使本次权重更小
+	if l.waiters.Len() == 0 && l.fits(n) {
+		l.cur += n
+		l.m.Unlock()
+		return nil
+	}
+
+	w := waiter{n: n, priority: priority, ready: make(chan struct{})}
+	elem := l.push(w)
+	//排到了最前面, 可能已经可以放行
+	if l.waiters.Front() == elem {
+		l.notifyWaiters()
+	}
+	l.m.Unlock()
+
+	select {
+	case <-w.ready:
+		//release() 已经替本次 acquire 计数; ctx同时结束时退还
+		if e := ctx.Err(); e != nil {
+			l.releaseLocal(n)
+			return e
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	select {
+	case <-w.ready:
+		//ctx结束的同时已经取得, 退还
+		l.cur -= n
+		l.notifyWaiters()
+		return ctx.Err()
+	default:
+	}
+
+	//排在最前面的等待者离开, 后面较小的权重可能已经可以放行
+	isFront := l.waiters.Front() == elem
+	l.waiters.Remove(elem)
+	if isFront {
+		l.notifyWaiters()
+	}
+	return ctx.Err()
+}
+
+func (l *limiter) releaseLocal(n uint64) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	l.cur -= n
+	l.notifyWaiters()
+}
+
+//变大时立即放行等待者; 变小时已运行的任务不受影响, 直到运行数低于新的size才放行
+func (l *limiter) setSize(n uint64) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	l.size = n
+	l.notifyWaiters()
+}
+
+func (l *limiter) getSize() uint64 {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	return l.size
+}
+
+//排在优先级不低于w的等待者之后. 调用时持有锁
+func (l *limiter) push(w waiter) *list.Element {
+	for e := l.waiters.Back(); e != nil; e = e.Prev() {
+		if e.Value.(waiter).priority >= w.priority {
+			return l.waiters.InsertAfter(w, e)
+		}
+	}
+	return l.waiters.PushFront(w)
+}
+
+func (l *limiter) fits(n uint64) bool {
+	return l.size == 0 || l.cur+n <= l.size
+}
+
+func (l *limiter) notifyWaiters() {
+	for {
+		next := l.waiters.Front()
+		if next == nil {
+			return
+		}
+
+		w := next.Value.(waiter)
+		if !l.fits(w.n) {
+			return
+		}
+		l.cur += w.n
+		l.waiters.Remove(next)
+		close(w.ready)
+	}
+}
@@ -0,0 +1,322 @@
+package group
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//等待者进入列队后才返回，保证入队顺序
+func waitQueued(l *limiter, n int) {
+	for {
+		l.m.Lock()
+		c := l.waiters.Len()
+		l.m.Unlock()
+		if c >= n {
+			return
+		}
+		runtime.Gosched()
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	var running, peak int64
+	l := limiter{size: 5}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.acquire(context.Background(), 1, 0)
+			defer l.release(1)
+
+			n := atomic.AddInt64(&running, 1)
+			for {
+				p := atomic.LoadInt64(&peak)
+				if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt64(&running, -1)
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 5, peak)
+	assert.EqualValues(t, 0, l.cur)
+	assert.Equal(t, 0, l.waiters.Len())
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	l := limiter{}
+	for i := 0; i < 100; i++ {
+		l.acquire(context.Background(), 1, 0)
+	}
+	assert.EqualValues(t, 100, l.cur)
+}
+
+func TestLimiterFIFO(t *testing.T) {
+	var order []int
+	m := sync.Mutex{}
+	l := limiter{size: 1}
+	l.acquire(context.Background(), 1, 0)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l.acquire(context.Background(), 1, 0)
+			m.Lock()
+			order = append(order, i)
+			m.Unlock()
+			l.release(1)
+		}(i)
+		waitQueued(&l, i+1)
+	}
+	l.release(1)
+	wg.Wait()
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, order)
+}
+
+func TestGroupMaxNoLeak(t *testing.T) {
+	var running, peak int64
+	before := runtime.NumGoroutine()
+
+	g := NewGroup()
+	g.SetMaxGoroutine(10)
+	for i := 0; i < 500; i++ {
+		g.Go(func() error {
+			n := atomic.AddInt64(&running, 1)
+			defer atomic.AddInt64(&running, -1)
+			for {
+				p := atomic.LoadInt64(&peak)
+				if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(100 * time.Microsecond)
+			return nil
+		})
+	}
+	g.Wait()
+
+	assert.True(t, peak <= 10)
+	assert.EqualValues(t, 500, g.GetGoroutineNum())
+
+	//任务结束后不会遗留协程
+	time.Sleep(10 * time.Millisecond)
+	assert.True(t, runtime.NumGoroutine() <= before)
+}
+
+func TestLimiterResize(t *testing.T) {
+	l := limiter{size: 1}
+	l.acquire(context.Background(), 1, 0)
+
+	var admitted int64
+	for i := 0; i < 4; i++ {
+		go func() {
+			l.acquire(context.Background(), 1, 0)
+			atomic.AddInt64(&admitted, 1)
+		}()
+	}
+	waitQueued(&l, 4)
+
+	//调大，等待者立即放行
+	l.setSize(3)
+	waitQueued(&l, 2)
+	for atomic.LoadInt64(&admitted) != 2 {
+		runtime.Gosched()
+	}
+
+	//调小，运行中的不受影响，运行数低于新的size前不放行
+	l.setSize(1)
+	l.release(1)
+	l.release(1)
+	assert.EqualValues(t, 2, atomic.LoadInt64(&admitted))
+	assert.Equal(t, 2, l.waiters.Len())
+
+	l.release(1)
+	for atomic.LoadInt64(&admitted) != 3 {
+		runtime.Gosched()
+	}
+
+	//调为0不限制
+	l.setSize(0)
+	for atomic.LoadInt64(&admitted) != 4 {
+		runtime.Gosched()
+	}
+}
+
+func TestGroupSetMaxGoroutineRuntime(t *testing.T) {
+	block := make(chan struct{})
+
+	g := NewGroup()
+	g.SetMaxGoroutine(1)
+	go func() {
+		for i := 0; i < 10; i++ {
+			g.Go(func() error { <-block; return nil })
+		}
+	}()
+	waitQueued(&g.limiter, 1)
+	assert.EqualValues(t, 1, g.GetGoroutineNum())
+
+	g.SetMaxGoroutine(5)
+	waitQueued(&g.limiter, 1)
+	for g.GetGoroutineNum() != 5 {
+		runtime.Gosched()
+	}
+	close(block)
+	g.Wait()
+	assert.EqualValues(t, 10, g.GetGoroutineNum())
+}
+
+func TestLimiterWeighted(t *testing.T) {
+	l := limiter{size: 10}
+	l.acquire(context.Background(), 8, 0)
+
+	var admitted int64
+	go func() {
+		l.acquire(context.Background(), 5, 0)
+		atomic.AddInt64(&admitted, 1)
+	}()
+	waitQueued(&l, 1)
+
+	//排队中的大任务优先, 小任务不能插队
+	go func() {
+		l.acquire(context.Background(), 1, 0)
+		atomic.AddInt64(&admitted, 1)
+	}()
+	waitQueued(&l, 2)
+	assert.EqualValues(t, 0, atomic.LoadInt64(&admitted))
+
+	l.release(8)
+	for atomic.LoadInt64(&admitted) != 2 {
+		runtime.Gosched()
+	}
+	assert.EqualValues(t, 6, l.cur)
+}
+
+func TestLimiterParent(t *testing.T) {
+	root := limiter{size: 2}
+	a := limiter{parent: &root}
+	b := limiter{size: 1, parent: &root}
+
+	a.acquire(context.Background(), 1, 0)
+	b.acquire(context.Background(), 1, 0)
+	assert.EqualValues(t, 2, root.cur)
+
+	var admitted int64
+	go func() {
+		a.acquire(context.Background(), 1, 0)
+		atomic.AddInt64(&admitted, 1)
+	}()
+	waitQueued(&root, 1)
+	assert.EqualValues(t, 0, atomic.LoadInt64(&admitted))
+
+	b.release(1)
+	for atomic.LoadInt64(&admitted) != 1 {
+		runtime.Gosched()
+	}
+	assert.EqualValues(t, 2, a.cur)
+	assert.EqualValues(t, 0, b.cur)
+	assert.EqualValues(t, 2, root.cur)
+
+	assert.True(t, b.tooHeavy(2))
+	assert.True(t, a.tooHeavy(3))
+	assert.False(t, a.tooHeavy(2))
+}
+
+func TestLimiterContext(t *testing.T) {
+	l := limiter{size: 10}
+	l.acquire(context.Background(), 8, 0)
+
+	//排在最前面的大任务放弃后, 后面的小任务立即放行
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() { errs <- l.acquire(ctx, 5, 0) }()
+	waitQueued(&l, 1)
+
+	admitted := make(chan struct{})
+	go func() {
+		l.acquire(context.Background(), 2, 0)
+		close(admitted)
+	}()
+	waitQueued(&l, 2)
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-errs)
+	<-admitted
+	assert.EqualValues(t, 10, l.cur)
+	assert.Equal(t, 0, l.waiters.Len())
+}
+
+func TestLimiterTryAcquire(t *testing.T) {
+	root := limiter{size: 2}
+	a := limiter{parent: &root}
+
+	assert.True(t, a.tryAcquire(2))
+	assert.False(t, a.tryAcquire(1))
+	//祖先取不到时退还本地
+	assert.EqualValues(t, 2, a.cur)
+
+	a.release(2)
+	assert.True(t, root.tryAcquire(1))
+	assert.False(t, a.tryAcquire(2))
+	assert.EqualValues(t, 0, a.cur)
+}
+
+func TestLimiterPriority(t *testing.T) {
+	var order []int
+	m := sync.Mutex{}
+	l := limiter{size: 1}
+	l.acquire(context.Background(), 1, 0)
+
+	wg := sync.WaitGroup{}
+	for i, priority := range []int{0, 1, 5, 1, 0, 5} {
+		wg.Add(1)
+		go func(i, priority int) {
+			defer wg.Done()
+			l.acquire(context.Background(), 1, priority)
+			m.Lock()
+			order = append(order, i)
+			m.Unlock()
+			l.release(1)
+		}(i, priority)
+		waitQueued(&l, i+1)
+	}
+	l.release(1)
+	wg.Wait()
+
+	assert.Equal(t, []int{2, 5, 1, 3, 0, 4}, order)
+}
+
+func TestLimiterPriorityWeighted(t *testing.T) {
+	l := limiter{size: 10}
+	l.acquire(context.Background(), 8, 0)
+
+	go l.acquire(context.Background(), 5, 0)
+	waitQueued(&l, 1)
+
+	//优先级更高并且放得下, 不用等前面的大任务
+	admitted := make(chan struct{})
+	go func() {
+		l.acquire(context.Background(), 2, 1)
+		close(admitted)
+	}()
+	<-admitted
+	assert.EqualValues(t, 10, l.cur)
+	assert.Equal(t, 1, l.waiters.Len())
+
+	l.release(8)
+	for l.waiters.Len() != 0 {
+		runtime.Gosched()
+	}
+}
@@ -0,0 +1,91 @@
+package group
+
+import (
+	"context"
+	"time"
+)
+
+//g.Go() g.GoCtx() 提交任务时的选项
+type Option func(t *task)
+
+//任务名, 记录在 TaskError.Name, 默认为函数名
+func WithName(name string) Option {
+	return func(t *task) {
+		t.name = name
+	}
+}
+
+//任务结束后注册回滚函数, 上下文组触发回滚时调用, 独立组不回滚
+func WithRollback(rollback ...func() error) Option {
+	return func(t *task) {
+		for _, f := range rollback {
+			t.rollback = append(t.rollback, f)
+		}
+	}
+}
+
+//任务的ctx在timeout后结束, 超时记录为 *TimeoutError, 与其他错误一样按错误策略取消ctx并回滚; 与 WithRetry() 同时使用时每次运行单独计时
+func WithTimeout(timeout time.Duration) Option {
+	return func(t *task) {
+		t.timeout = timeout
+		t.isTransientTimeout = false
+	}
+}
+
+//同 WithTimeout(), 但超时是暂时性错误, 只记录到 errs, 不取消同组的其他任务也不回滚
+func WithTransientTimeout(timeout time.Duration) Option {
+	return func(t *task) {
+		t.timeout = timeout
+		t.isTransientTimeout = true
+	}
+}
+
+//任务返回错误时按r重试, 见 Retry
+func WithRetry(r Retry) Option {
+	return func(t *task) {
+		t.retry = &r
+	}
+}
+
+//达到 SetMaxGoroutine() 的上限时, 等待空闲位置期间ctx结束则放弃提交, g.Go() 返回 ctx.Err(); ctx已经结束时有空闲位置也不提交
+//ctx只用于等待, 不传给任务
+func WithWaitContext(ctx context.Context) Option {
+	return func(t *task) {
+		t.waitCtx = ctx
+	}
+}
+
+//等待空闲位置时, priority 高的任务先运行, 相同优先级按调用顺序, 默认为0
+//Executor 实现了 PriorityExecutor 时(如 *pool.Pool), 在Executor中排队也按优先级
+func WithPriority(priority int) Option {
+	return func(t *task) {
+		t.priority = priority
+	}
+}
+
+//任务的权重, 默认为1, 此时 SetMaxGoroutine(n) 的n是本组的总预算, 运行中任务的权重之和不超过n
+//weight 大于当前预算(含共享的祖先预算)时提交返回错误; 等待期间预算被调小到weight以下, 会一直等到预算调大
+//每个任务都占用预算, weight为0时按1计算
+func WithWeight(weight uint64) Option {
+	return func(t *task) {
+		if weight == 0 {
+			weight = 1
+		}
+		t.weight = weight
+	}
+}
+
+//任务名取自fn, 用于包装了用户函数的入口
+func withFn(fn interface{}) Option {
+	return func(t *task) {
+		t.fn = fn
+	}
+}
+
+func newTask(fn interface{}, opts []Option) *task {
+	t := &task{fn: fn, weight: 1}
+	for _, o := range opts {
+		o(t)
+	}
+	return t
+}
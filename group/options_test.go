@@ -0,0 +1,107 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGoAnyGroup(t *testing.T) {
+	f := class{a: 1}
+
+	//上下文组中 g.Go()
+	g := NewGroup()
+	g.WithContext(context.TODO())
+	assert.NoError(t, g.Go(func() error { return nil }, WithRollback(f.funcResetA)))
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error { return errTest }))
+	assert.Len(t, g.Wait(), 1)
+	assert.Equal(t, 0, f.a)
+
+	//独立组中 g.GoCtx()
+	var ctxErr error
+	g = NewGroup()
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error {
+		ctxErr = ctx.Err()
+		return errTest
+	}, WithRollback(f.funcResetB)))
+	f.b = 1
+	assert.Len(t, g.Wait(), 1)
+	assert.NoError(t, ctxErr)
+	//独立组不回滚
+	assert.Equal(t, 1, f.b)
+}
+
+func TestWithName(t *testing.T) {
+	g := NewGroup()
+	assert.NoError(t, g.Go(testReturn, WithName("load user")))
+	assert.NoError(t, g.Go(testReturn, WithRetry(Retry{MaxAttempts: 2})))
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error { return testReturn() }, WithTimeout(time.Second)))
+
+	var names []string
+	for _, e := range g.Wait() {
+		var te *TaskError
+		assert.True(t, errors.As(e, &te))
+		names = append(names, te.Name)
+	}
+	//包装后仍取用户函数的名字
+	assert.ElementsMatch(t, []string{
+		"load user",
+		"github.com/XeiTongXueFlyMe/poolgroup/group.testReturn",
+		"github.com/XeiTongXueFlyMe/poolgroup/group.TestWithName.func1",
+	}, names)
+}
+
+func TestWithTimeoutRetry(t *testing.T) {
+	var count int64
+
+	begin := time.Now()
+	g := NewGroup()
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error {
+		atomic.AddInt64(&count, 1)
+		return slowTask(ctx)
+	}, WithTimeout(10*time.Millisecond), WithRetry(Retry{MaxAttempts: 3})))
+
+	errs := g.Wait()
+	//每次运行单独计时
+	assert.EqualValues(t, 3, count)
+	assert.True(t, time.Since(begin) >= 30*time.Millisecond)
+	assert.Len(t, errs, 1)
+
+	var re *RetryError
+	assert.True(t, errors.As(errs[0], &re))
+	assert.Len(t, re.Errs, 3)
+	for _, e := range re.Errs {
+		var to *TimeoutError
+		assert.True(t, errors.As(e, &to))
+	}
+	assert.False(t, IsTransient(errs[0]))
+}
+
+func TestDeprecatedGo(t *testing.T) {
+	f := class{a: 1}
+	var count int64
+
+	g := NewGroup()
+	g.WithContext(context.TODO())
+	assert.NoError(t, g.GoFunc(f.funcCtxC, "not a rollback", f.funcResetA))
+	assert.NoError(t, g.GoFunc(func() error { return nil }))
+	assert.NoError(t, g.GoContext(context.TODO(), f.funcCtxC))
+	assert.NoError(t, g.GoWeighted(1, f.funcCtxC))
+	assert.NoError(t, g.GoPriority(1, f.funcCtxC))
+	assert.NoError(t, g.GoRetry(Retry{MaxAttempts: 2}, func(ctx context.Context) error {
+		atomic.AddInt64(&count, 1)
+		return errTest
+	}))
+	assert.NoError(t, g.GoTimeout(time.Millisecond, slowTask))
+	assert.EqualError(t, g.GoFunc(func() {}), GO_F_TYPE_ERR)
+	assert.EqualError(t, g.GoTimeout(time.Second, func() error { return nil }), GO_F_TYPE_ERR)
+
+	errs := g.Wait()
+	assert.Len(t, errs, 2)
+	assert.EqualValues(t, 2, count)
+	//忽略不是 func() error 的回滚函数
+	assert.Equal(t, 0, f.a)
+}
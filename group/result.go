@@ -0,0 +1,66 @@
+package group
+
+import (
+	"context"
+	"sync"
+)
+
+//任务返回 (T, error) 的 Group, r.Wait() 按提交顺序返回结果
+//配置(SetMaxGoroutine, WithContext, SetPool 等)和派生子组直接使用内嵌的 *Group, 子组任务的结果不收集
+type ResultGroup[T any] struct {
+	*Group
+
+	rm      sync.Mutex
+	results []T
+}
+
+func NewResultGroup[T any]() *ResultGroup[T] {
+	return &ResultGroup[T]{Group: NewGroup()}
+}
+
+//同 g.Go(), 任务返回错误, panic 或未能运行时, 结果为T的零值
+func (r *ResultGroup[T]) Go(f func() (T, error), opts ...Option) error {
+	i := r.slot()
+	return r.Group.Go(func() error {
+		res, e := f()
+		r.set(i, res)
+		return e
+	}, append([]Option{withFn(f)}, opts...)...)
+}
+
+//同 g.GoCtx(), 其余同 r.Go()
+func (r *ResultGroup[T]) GoCtx(f func(ctx context.Context) (T, error), opts ...Option) error {
+	i := r.slot()
+	return r.Group.GoCtx(func(ctx context.Context) error {
+		res, e := f(ctx)
+		r.set(i, res)
+		return e
+	}, append([]Option{withFn(f)}, opts...)...)
+}
+
+//阻塞直到派生树全部结束, 返回本组任务的结果(按 r.Go() 的调用顺序)和整个派生树的错误
+func (r *ResultGroup[T]) Wait() ([]T, []error) {
+	errs := r.Group.Wait()
+
+	r.rm.Lock()
+	defer r.rm.Unlock()
+
+	return append([]T(nil), r.results...), errs
+}
+
+//为一个任务预留结果的位置
+func (r *ResultGroup[T]) slot() int {
+	r.rm.Lock()
+	defer r.rm.Unlock()
+
+	var zero T
+	r.results = append(r.results, zero)
+	return len(r.results) - 1
+}
+
+func (r *ResultGroup[T]) set(i int, v T) {
+	r.rm.Lock()
+	defer r.rm.Unlock()
+
+	r.results[i] = v
+}
@@ -0,0 +1,57 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestResultGroup(t *testing.T) {
+	r := NewResultGroup[int]()
+	r.SetMaxGoroutine(4)
+
+	for i := 0; i < 20; i++ {
+		i := i
+		assert.NoError(t, r.Go(func() (int, error) {
+			//越早提交的任务越晚结束
+			time.Sleep(time.Duration(20-i) * time.Millisecond)
+			return i * i, nil
+		}))
+	}
+
+	res, errs := r.Wait()
+	assert.Len(t, errs, 0)
+	assert.Len(t, res, 20)
+	for i, v := range res {
+		assert.Equal(t, i*i, v)
+	}
+}
+
+func TestResultGroupErr(t *testing.T) {
+	r := NewResultGroup[string]()
+	assert.NoError(t, r.Go(func() (string, error) { return "a", nil }))
+	assert.NoError(t, r.Go(func() (string, error) { return "b", errors.New("err") }))
+	assert.NoError(t, r.Go(func() (string, error) { panic("panic") }))
+	assert.NoError(t, r.Go(func() (string, error) { return "d", nil }))
+
+	res, errs := r.Wait()
+	assert.Equal(t, []string{"a", "b", "", "d"}, res)
+	assert.Len(t, errs, 2)
+}
+
+func TestResultGroupContext(t *testing.T) {
+	f := class{a: 1}
+
+	r := NewResultGroup[int]()
+	r.WithContext(context.TODO())
+	assert.NoError(t, r.GoCtx(func(ctx context.Context) (int, error) { return 1, nil }, WithRollback(f.funcResetA)))
+	assert.NoError(t, r.GoCtx(func(ctx context.Context) (int, error) { return 2, f.funcTimeOut(ctx) }))
+
+	res, errs := r.Wait()
+	assert.Equal(t, []int{1, 2}, res)
+	assert.Len(t, errs, 1)
+	//有任务失败, 执行回滚
+	assert.Equal(t, 0, f.a)
+}
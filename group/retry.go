@@ -0,0 +1,117 @@
+package group
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+//WithRetry() 的重试方式
+type Retry struct {
+	//最多运行的次数, 包括第一次, 小于2时不重试
+	MaxAttempts int
+	//第一次重试前的等待时间
+	Backoff time.Duration
+	//等待时间的上限, 0为不限制
+	MaxBackoff time.Duration
+	//每次重试后等待时间乘以Multiplier, 小于1时为2
+	Multiplier float64
+	//等待时间在 [1-Jitter, 1+Jitter] 倍之间随机, 避免大量任务同时重试, 取值 [0, 1]
+	Jitter float64
+	//返回false的错误不再重试, nil时全部重试; 只重试暂时性错误时设为 IsTransient
+	RetryIf func(err error) bool
+}
+
+//WithRetry() 的任务最终失败时记录的错误, 包含每一次运行的错误
+type RetryError struct {
+	Errs []error
+	//等待重试时ctx结束, 为 ctx.Err(), 不算一次运行
+	Ctx error
+}
+
+func (e *RetryError) Error() string {
+	s := fmt.Sprintf("after %d attempts: %v", len(e.Errs), e.Errs[len(e.Errs)-1])
+	if e.Ctx != nil {
+		s = fmt.Sprintf("%s; stop retrying: %v", s, e.Ctx)
+	}
+	return s
+}
+
+func (e *RetryError) Unwrap() []error {
+	if e.Ctx != nil {
+		return append(append([]error(nil), e.Errs...), e.Ctx)
+	}
+	return e.Errs
+}
+
+//按最后一次运行的错误判断
+func (e *RetryError) Transient() bool {
+	return IsTransient(e.Errs[len(e.Errs)-1])
+}
+
+//第n次重试前的等待时间, n从1开始
+func (r *Retry) backoff(n int) time.Duration {
+	m := r.Multiplier
+	if m < 1 {
+		m = 2
+	}
+
+	//MaxBackoff为0时以 time.Duration 的最大值为上限, 避免溢出为负数
+	max := float64(math.MaxInt64)
+	if r.MaxBackoff > 0 {
+		max = float64(r.MaxBackoff)
+	}
+
+	d := float64(r.Backoff)
+	for i := 1; i < n && d < max; i++ {
+		d *= m
+	}
+	if d > max {
+		d = max
+	}
+	if r.Jitter > 0 {
+		d *= 1 + r.Jitter*(2*rand.Float64()-1)
+	}
+	//float64(math.MaxInt64) 转换回 int64 会溢出
+	if d >= float64(math.MaxInt64) {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
+
+//运行f直到成功, 次数用完, RetryIf返回false或ctx结束; panic不重试
+func (r *Retry) do(ctx context.Context, f func() error) error {
+	var errs []error
+	for n := 1; ; n++ {
+		e := f()
+		if e == nil {
+			return nil
+		}
+		errs = append(errs, e)
+
+		if n >= r.MaxAttempts || (r.RetryIf != nil && !r.RetryIf(e)) {
+			return &RetryError{Errs: errs}
+		}
+
+		timer := time.NewTimer(r.backoff(n))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return &RetryError{Errs: errs, Ctx: ctx.Err()}
+		}
+	}
+}
+
+func (r *Retry) wrap(f func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return r.do(ctx, func() error { return f(ctx) })
+	}
+}
+
+//Deprecated: 使用 g.Go() 或 g.GoCtx() 和 WithRetry()
+func (g *Group) GoRetry(r Retry, f interface{}, rollback ...interface{}) error {
+	return g.goFunc(f, rollback, WithRetry(r))
+}
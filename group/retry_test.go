@@ -0,0 +1,124 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//前n次返回错误
+func failTimes(n int64, count *int64) func() error {
+	return func() error {
+		if atomic.AddInt64(count, 1) <= n {
+			return errTest
+		}
+		return nil
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	var ok, failed int64
+	r := Retry{MaxAttempts: 3, Backoff: 5 * time.Millisecond}
+
+	begin := time.Now()
+	g := NewGroup()
+	assert.NoError(t, g.Go(failTimes(2, &ok), WithRetry(r)))
+	assert.NoError(t, g.Go(failTimes(5, &failed), WithRetry(r)))
+
+	errs := g.Wait()
+	//5ms + 10ms
+	assert.True(t, time.Since(begin) >= 15*time.Millisecond)
+	assert.EqualValues(t, 3, ok)
+	assert.EqualValues(t, 3, failed)
+
+	//只记录最终失败的任务
+	assert.Len(t, errs, 1)
+	var re *RetryError
+	assert.True(t, errors.As(errs[0], &re))
+	assert.Equal(t, []error{errTest, errTest, errTest}, re.Errs)
+	assert.True(t, errors.Is(errs[0], errTest))
+	assert.Contains(t, errs[0].Error(), "after 3 attempts: test")
+
+	var te *TaskError
+	assert.True(t, errors.As(errs[0], &te))
+	assert.Contains(t, te.Name, "failTimes")
+}
+
+func TestWithRetryIf(t *testing.T) {
+	var count int64
+	fatal := errors.New("fatal")
+
+	g := NewGroup()
+	assert.NoError(t, g.Go(func() error {
+		if atomic.AddInt64(&count, 1) == 2 {
+			return fatal
+		}
+		return errTest
+	}, WithRetry(Retry{
+		MaxAttempts: 10,
+		RetryIf:     func(err error) bool { return err != fatal },
+	})))
+
+	errs := g.Wait()
+	assert.EqualValues(t, 2, count)
+	var re *RetryError
+	assert.True(t, errors.As(errs[0], &re))
+	assert.Equal(t, []error{errTest, fatal}, re.Errs)
+}
+
+func TestWithRetryContext(t *testing.T) {
+	var count int64
+
+	g := NewGroup()
+	g.WithContext(context.TODO())
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error {
+		atomic.AddInt64(&count, 1)
+		return errTest
+	}, WithRetry(Retry{MaxAttempts: 100, Backoff: time.Hour})))
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error {
+		time.Sleep(10 * time.Millisecond)
+		return errors.New("err")
+	}))
+
+	//ctx被取消, 不再等待重试
+	errs := g.Wait()
+	assert.EqualValues(t, 1, count)
+	assert.Len(t, errs, 2)
+	assert.True(t, errors.Is(errs[1], context.Canceled))
+	assert.True(t, errors.Is(errs[1], errTest))
+	//ctx结束不算一次运行
+	var re *RetryError
+	assert.True(t, errors.As(errs[1], &re))
+	assert.Equal(t, []error{errTest}, re.Errs)
+	assert.Equal(t, context.Canceled, re.Ctx)
+	assert.Contains(t, re.Error(), "after 1 attempts: test; stop retrying: context canceled")
+}
+
+func TestRetryBackoff(t *testing.T) {
+	r := Retry{Backoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond, Multiplier: 3}
+	assert.Equal(t, 10*time.Millisecond, r.backoff(1))
+	assert.Equal(t, 30*time.Millisecond, r.backoff(2))
+	assert.Equal(t, 50*time.Millisecond, r.backoff(3))
+	assert.Equal(t, 50*time.Millisecond, r.backoff(100))
+
+	r = Retry{Backoff: 10 * time.Millisecond}
+	assert.Equal(t, 40*time.Millisecond, r.backoff(3))
+
+	//MaxBackoff为0时不溢出
+	r = Retry{Backoff: 100 * time.Millisecond}
+	for _, n := range []int{45, 100, 10000} {
+		assert.Equal(t, time.Duration(math.MaxInt64), r.backoff(n))
+	}
+	r = Retry{Backoff: 100 * time.Millisecond, Jitter: 0.5}
+	assert.True(t, r.backoff(100) > 0)
+
+	r = Retry{Backoff: 100 * time.Millisecond, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		d := r.backoff(1)
+		assert.True(t, d >= 50*time.Millisecond && d <= 150*time.Millisecond)
+	}
+}
@@ -0,0 +1,55 @@
+package group
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+//WithTimeout() WithTransientTimeout() 的任务超时时记录的错误
+type TimeoutError struct {
+	Timeout time.Duration
+	//任务返回的错误, 通常是 context.DeadlineExceeded; 超时后仍返回nil时为nil
+	Err error
+
+	transient bool
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("timeout after %v", e.Timeout)
+	}
+	return fmt.Sprintf("timeout after %v: %v", e.Timeout, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+//WithTransientTimeout() 的超时是暂时性错误, WithTimeout() 的超时是致命错误
+func (e *TimeoutError) Transient() bool {
+	return e.transient
+}
+
+//ctx 在 timeout 后结束, f 运行超过 timeout 时返回 *TimeoutError, parent 结束导致的错误照常返回
+func timeoutFunc(timeout time.Duration, transient bool, f func(ctx context.Context) error) func(parent context.Context) error {
+	return func(parent context.Context) error {
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		defer cancel()
+
+		e := f(ctx)
+		if ctx.Err() == context.DeadlineExceeded && parent.Err() == nil {
+			return &TimeoutError{Timeout: timeout, Err: e, transient: transient}
+		}
+		return e
+	}
+}
+
+//Deprecated: 使用 g.GoCtx() 和 WithTimeout()
+//f 只支持 .(func(ctx context.Context) error), 独立组中从 context.Background() 派生
+func (g *Group) GoTimeout(timeout time.Duration, f interface{}, rollback ...interface{}) error {
+	if _, ok := f.(func(ctx context.Context) error); !ok {
+		return ErrFuncType
+	}
+	return g.goFunc(f, rollback, WithTimeout(timeout))
+}
@@ -0,0 +1,116 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func slowTask(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Second):
+		return nil
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	f := class{a: 1}
+
+	g := NewGroup()
+	g.WithContext(context.TODO())
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error { return nil }, WithRollback(f.funcResetA)))
+	assert.NoError(t, g.GoCtx(slowTask, WithTimeout(10*time.Millisecond)))
+	assert.NoError(t, g.GoCtx(slowTask))
+
+	errs := g.Wait()
+	assert.Len(t, errs, 2)
+	var kinds []ErrKind
+	for _, e := range errs {
+		var te *TaskError
+		assert.True(t, errors.As(e, &te))
+		kinds = append(kinds, te.Kind)
+		if te.Kind == KindTimeout {
+			assert.False(t, IsTransient(e))
+		} else {
+			assert.Equal(t, context.Canceled, te.Err)
+		}
+	}
+	assert.ElementsMatch(t, []ErrKind{KindTimeout, KindReturn}, kinds)
+
+	//默认超时是致命错误, 取消本组的其他任务并回滚
+	assert.Equal(t, 0, f.a)
+}
+
+func TestWithTransientTimeout(t *testing.T) {
+	f := class{a: 1}
+
+	g := NewGroup()
+	g.WithContext(context.TODO())
+	assert.NoError(t, g.GoCtx(slowTask, WithTransientTimeout(10*time.Millisecond), WithRollback(f.funcResetA)))
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error { return nil }, WithTimeout(time.Second)))
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(30 * time.Millisecond):
+			return nil
+		}
+	}))
+
+	errs := g.Wait()
+	assert.Len(t, errs, 1)
+
+	var te *TaskError
+	assert.True(t, errors.As(errs[0], &te))
+	assert.Equal(t, KindTimeout, te.Kind)
+	assert.Contains(t, te.Name, "slowTask")
+	var to *TimeoutError
+	assert.True(t, errors.As(errs[0], &to))
+	assert.Equal(t, 10*time.Millisecond, to.Timeout)
+	assert.True(t, errors.Is(errs[0], context.DeadlineExceeded))
+	assert.Contains(t, errs[0].Error(), "timeout after 10ms: context deadline exceeded")
+	assert.True(t, IsTransient(errs[0]))
+
+	//超时不取消本组的其他任务, 不回滚
+	assert.Equal(t, 1, f.a)
+}
+
+func TestWithTimeoutIndependent(t *testing.T) {
+	g := NewGroup()
+	assert.NoError(t, g.GoCtx(slowTask, WithTimeout(10*time.Millisecond)))
+	//任务不理会ctx, 超时后返回nil也记录
+	assert.NoError(t, g.GoCtx(func(ctx context.Context) error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}, WithTimeout(time.Millisecond)))
+
+	errs := g.Wait()
+	assert.Len(t, errs, 2)
+	for _, e := range errs {
+		var to *TimeoutError
+		assert.True(t, errors.As(e, &to))
+		if to.Timeout == time.Millisecond {
+			assert.Nil(t, to.Err)
+			assert.Contains(t, e.Error(), "timeout after 1ms")
+		}
+	}
+}
+
+func TestWithTimeoutParentCancel(t *testing.T) {
+	g := NewGroup()
+	c := g.WithTimeout(context.TODO(), 10*time.Millisecond)
+	assert.NoError(t, g.GoCtx(slowTask, WithTimeout(time.Second)))
+
+	//本组的ctx结束, 不是任务超时
+	errs := g.Wait()
+	assert.Len(t, errs, 1)
+	var te *TaskError
+	assert.True(t, errors.As(errs[0], &te))
+	assert.Equal(t, KindReturn, te.Kind)
+	assert.Equal(t, context.DeadlineExceeded, te.Err)
+	assert.Equal(t, context.DeadlineExceeded, c.Err())
+}
@@ -113,8 +113,8 @@ func example_4() error {
 	g := group.NewGroup()
 	g.WithContext(context.TODO())
 	//g.WithTimeout(context.TODO(), 200*time.Millisecond)
-	g.Go(c.IncreasedCtx)
-	g.Go(c.PrintValueCtx)
+	g.GoCtx(c.IncreasedCtx)
+	g.GoCtx(c.PrintValueCtx)
 
 	g.Wait()
 
@@ -148,19 +148,19 @@ func example_6() error {
 
 	g := group.NewGroup()
 	g.WithContext(context.TODO())
-	g.Go(c.IncreasedCtx)
-	g.Go(c.TimeOutErr)
+	g.GoCtx(c.IncreasedCtx)
+	g.GoCtx(c.TimeOutErr)
 
 	A := g.ForkChild()
-	A.Go(c.IncreasedCtx)
+	A.GoCtx(c.IncreasedCtx)
 	B := g.ForkChild()
-	B.Go(c.IncreasedCtx)
+	B.GoCtx(c.IncreasedCtx)
 
 	a := A.ForkChild()
-	a.Go(c.IncreasedCtx)
+	a.GoCtx(c.IncreasedCtx)
 	b := A.ForkChild()
-	b.Go(c.IncreasedCtx)
-	b.Go(c.IncreasedCtx)
+	b.GoCtx(c.IncreasedCtx)
+	b.GoCtx(c.IncreasedCtx)
 
 	g.Wait()
 	fmt.Println("所有协程全部退出")
@@ -207,8 +207,8 @@ func example_7() error {
 	c := db{}
 	g := group.NewGroup()
 	g.WithContext(context.TODO())
-	g.Go(c.AddFile, c.DelAllFile)
-	g.Go(c.PrintFileMeta)
+	g.GoCtx(c.AddFile, group.WithRollback(c.DelAllFile))
+	g.GoCtx(c.PrintFileMeta)
 
 	g.Wait()
 
@@ -235,6 +235,16 @@ func example_8() error {
 	return nil
 }
 
+//同 example_8
+func example_9() error {
+	a := []string{"h", "i", "m"}
+	b := "immm"
+
+	return group.ForEach(context.TODO(), a, 2, func(ctx context.Context, value string) error {
+		return myPrintf(value, b)
+	})
+}
+
 func main() {
 	g := group.NewGroup()
 	//g.Go(example_1)
@@ -245,6 +255,7 @@ func main() {
 	//g.Go(example_6)
 	//g.Go(example_7)
 	g.Go(example_8)
+	//g.Go(example_9)
 	g.GetErrs()
 
 	g.Wait()
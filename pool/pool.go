@@ -1,3 +1,490 @@
 package pool
 
-//TODO：通过列队，管道支持高并发
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	SUBMIT_F_TYPE_ERR   = "p.Submit(f): f.(type) is FAILURE"
+	POOL_STOPPED_ERR    = "err :calling p.Submit() after calling p.Stop()"
+	FUNC_CALL_LOGIC_ERR = "err :calling Configure after calling p.Submit()"
+	POOL_FULL_ERR       = "err :task queue of pool is full"
+	TASK_DROPPED_ERR    = "err :task is dropped, task queue of pool is full"
+)
+
+var (
+	//FullReject 时 p.Submit() 返回
+	ErrPoolFull = errors.New(POOL_FULL_ERR)
+	//FullDropOldest FullDropNewest 丢弃的任务, 记录在 p.GetErrs()
+	ErrTaskDropped = errors.New(TASK_DROPPED_ERR)
+)
+
+//任务panic时记录的错误, Error() 与 fmt.Sprint(Value) 相同
+type PanicError struct {
+	//panic() 的参数
+	Value interface{}
+	//recover 时的协程堆栈
+	Stack []byte
+}
+
+//在 recover() 所在的 defer 函数中调用, 堆栈才包含panic的位置
+func NewPanicError(v interface{}) *PanicError {
+	return &PanicError{Value: v, Stack: debug.Stack()}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprint(e.Value)
+}
+
+//panic(err) 时返回err, errors.Is() errors.As() 可以穿透
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+//任务列队满时的处理方式
+type FullPolicy int
+
+const (
+	//阻塞直到列队有空位, 默认值
+	FullBlock FullPolicy = iota
+	//不接收, 返回 ErrPoolFull
+	FullReject
+	//丢弃列队中优先级最低的任务里最早的一个, 接收新任务
+	FullDropOldest
+	//丢弃新任务
+	FullDropNewest
+	//在调用者的协程中直接运行新任务
+	FullCallerRuns
+)
+
+//常驻协程从任务列队中取任务执行, 默认数量固定, SetMinWorkerNum() 后随负载伸缩
+type Pool struct {
+	size      uint64
+	min       uint64
+	isElastic bool
+	idleTime  time.Duration
+	num       uint64
+	idle      uint64
+	isUsed    bool
+	isStop    bool
+	pending   uint64
+	queue     []job
+	maxLen    uint64
+	policy    FullPolicy
+	errs      []error
+	m         sync.Mutex
+	cond      *sync.Cond
+	notFull   *sync.Cond
+	done      *sync.Cond
+	workers   sync.WaitGroup
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+//列队中的任务, run 和 drop 只会调用其中一个
+type job struct {
+	run      func()
+	drop     func(err error)
+	priority int
+}
+
+//size 为常驻协程数量, 最少为1
+func NewPool(size uint64) *Pool {
+	if size == 0 {
+		size = 1
+	}
+
+	p := &Pool{}
+	p.cond = sync.NewCond(&p.m)
+	p.notFull = sync.NewCond(&p.m)
+	p.done = sync.NewCond(&p.m)
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	p.SetWorkerNum(size)
+	return p
+}
+
+//运行中调整常驻协程数量(伸缩时为最大数量), 最少为1
+//调大立即创建协程; 调小时多余的协程执行完手上的任务后退出
+func (p *Pool) SetWorkerNum(n uint64) {
+	if n == 0 {
+		n = 1
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.isStop {
+		return
+	}
+	p.size = n
+	if p.min > p.size {
+		p.min = p.size
+	}
+	p.grow()
+	p.cond.Broadcast()
+}
+
+//协程数量在 [n, SetWorkerNum()] 之间伸缩: 列队中有任务而没有空闲协程时创建新协程,
+//超过n的协程空闲 idleTime 后退出
+func (p *Pool) SetMinWorkerNum(n uint64, idleTime time.Duration) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.isStop {
+		return
+	}
+	p.isElastic = true
+	p.min = n
+	if p.min > p.size {
+		p.min = p.size
+	}
+	p.idleTime = idleTime
+	p.grow()
+	p.cond.Broadcast()
+}
+
+//列队最多缓存n个等待运行的任务, 列队满时按policy处理, n为0时不限制
+func (p *Pool) SetQueueSize(n uint64, policy FullPolicy) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	p.maxLen = n
+	p.policy = policy
+	p.notFull.Broadcast()
+}
+
+//f(ctx) 使用的上下文, p.Stop()时被取消
+func (p *Pool) WithContext(ctx context.Context) (c context.Context) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.isUsed {
+		panic(FUNC_CALL_LOGIC_ERR)
+	}
+	p.cancel()
+	c, p.cancel = context.WithCancel(ctx)
+	p.ctx = c
+	return
+}
+
+//f 支持 .(func() error) 和 .(func(ctx context.Context) error)
+//列队满时的行为见 SetQueueSize()
+func (p *Pool) Submit(f interface{}) error {
+	return p.SubmitPriority(0, f)
+}
+
+//同 p.Submit(), 列队中 priority 高的任务先运行, 相同优先级先进先出, p.Submit() 的优先级为0
+func (p *Pool) SubmitPriority(priority int, f interface{}) error {
+	var run func()
+
+	switch t := f.(type) {
+	case func() error:
+		run = func() { p.f(t) }
+	case func(ctx context.Context) error:
+		run = func() { p.fWithContext(t) }
+	default:
+		return errors.New(SUBMIT_F_TYPE_ERR)
+	}
+
+	return p.push(job{run: run, drop: p.collectErrs, priority: priority})
+}
+
+//task 在常驻协程中运行，task的错误由调用者自己处理，供group等上层调度使用
+//返回错误时task不会运行; 已接收的task被丢弃时, 不运行task, 改为调用drop
+func (p *Pool) Execute(task func(), drop func(err error)) error {
+	return p.ExecutePriority(0, task, drop)
+}
+
+//同 p.Execute(), 在列队中按priority排队
+func (p *Pool) ExecutePriority(priority int, task func(), drop func(err error)) error {
+	return p.push(job{
+		priority: priority,
+		run: func() {
+			defer func() {
+				if e := recover(); e != nil {
+					p.collectErrs(NewPanicError(e))
+				}
+			}()
+
+			task()
+		},
+		drop: drop,
+	})
+}
+
+//阻塞，直到已提交的任务全部执行完毕，pool继续可用
+func (p *Pool) Wait() []error {
+	p.m.Lock()
+	for p.pending > 0 {
+		p.done.Wait()
+	}
+	p.m.Unlock()
+
+	return p.GetErrs()
+}
+
+//不再接收新任务，执行完列队中的任务后，所有常驻协程退出
+func (p *Pool) Stop() {
+	p.m.Lock()
+	p.isStop = true
+	p.cond.Broadcast()
+	p.notFull.Broadcast()
+	p.m.Unlock()
+
+	p.workers.Wait()
+	p.cancel()
+}
+
+//当前常驻协程数量
+func (p *Pool) GetWorkerNum() uint64 {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	return p.num
+}
+
+func (p *Pool) GetErrs() []error {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	return append([]error(nil), p.errs...)
+}
+
+func (p *Pool) push(j job) error {
+	var dropped []job
+
+	p.m.Lock()
+	if p.isStop {
+		p.m.Unlock()
+		return errors.New(POOL_STOPPED_ERR)
+	}
+
+	if p.isFull() {
+		switch p.policy {
+		case FullReject:
+			p.m.Unlock()
+			return ErrPoolFull
+		case FullDropNewest:
+			p.isUsed = true
+			p.pending++
+			p.m.Unlock()
+			p.dropJob(j)
+			return nil
+		case FullCallerRuns:
+			p.isUsed = true
+			p.pending++
+			p.m.Unlock()
+			p.runJob(j)
+			return nil
+		case FullDropOldest:
+			for p.isFull() && len(p.queue) > 0 {
+				dropped = append(dropped, p.dropOldest())
+			}
+		default:
+			for p.isFull() && !p.isStop {
+				p.notFull.Wait()
+			}
+			if p.isStop {
+				p.m.Unlock()
+				return errors.New(POOL_STOPPED_ERR)
+			}
+		}
+	}
+
+	p.isUsed = true
+	p.pending++
+	p.enqueue(j)
+	p.grow()
+	p.cond.Signal()
+	p.m.Unlock()
+
+	//drop可能回调调用者, 不能持有锁
+	for _, v := range dropped {
+		p.dropJob(v)
+	}
+	return nil
+}
+
+//按优先级从高到低排列, 相同优先级先进先出. 调用时持有锁
+func (p *Pool) enqueue(j job) {
+	i := len(p.queue)
+	for i > 0 && p.queue[i-1].priority < j.priority {
+		i--
+	}
+
+	p.queue = append(p.queue, job{})
+	copy(p.queue[i+1:], p.queue[i:])
+	p.queue[i] = j
+}
+
+//移出优先级最低的任务中最早的一个. 调用时持有锁
+func (p *Pool) dropOldest() job {
+	last := len(p.queue) - 1
+	i := last
+	for i > 0 && p.queue[i-1].priority == p.queue[last].priority {
+		i--
+	}
+
+	j := p.queue[i]
+	copy(p.queue[i:], p.queue[i+1:])
+	p.queue[last] = job{}
+	p.queue = p.queue[:last]
+	return j
+}
+
+func (p *Pool) isFull() bool {
+	return p.maxLen != 0 && uint64(len(p.queue)) >= p.maxLen
+}
+
+func (p *Pool) runJob(j job) {
+	defer p.taskDone()
+	j.run()
+}
+
+func (p *Pool) dropJob(j job) {
+	defer p.taskDone()
+	if j.drop != nil {
+		j.drop(ErrTaskDropped)
+	}
+}
+
+//调用时持有锁
+func (p *Pool) minNum() uint64 {
+	if p.isElastic {
+		return p.min
+	}
+	return p.size
+}
+
+//补足最少的协程, 列队中的任务多于空闲协程时继续创建, 不超过size. 调用时持有锁
+func (p *Pool) grow() {
+	for p.num < p.minNum() || (p.num < p.size && uint64(len(p.queue)) > p.idle) {
+		p.num++
+		p.workers.Add(1)
+		go p.worker()
+	}
+}
+
+func (p *Pool) worker() {
+	defer p.workers.Done()
+
+	for {
+		p.m.Lock()
+		j, ok := p.next()
+		if !ok {
+			p.num--
+			p.m.Unlock()
+			return
+		}
+		p.m.Unlock()
+
+		p.runJob(j)
+	}
+}
+
+//取下一个任务, 返回false时本协程退出. 调用时持有锁
+func (p *Pool) next() (job, bool) {
+	var timer *time.Timer
+	idleSince := time.Now()
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		//p.SetWorkerNum()调小, 多余的协程退出
+		if p.num > p.size {
+			return job{}, false
+		}
+		if len(p.queue) > 0 {
+			break
+		}
+		//p.Stop()之后列队为空，退出
+		if p.isStop {
+			return job{}, false
+		}
+		//超过最少数量的协程，空闲超时退出
+		if p.num > p.minNum() {
+			idle := time.Since(idleSince)
+			if idle >= p.idleTime {
+				return job{}, false
+			}
+			//idleTime 可能已被修改, 每次都重新计时
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(p.idleTime-idle, p.wakeAll)
+		}
+
+		p.idle++
+		p.cond.Wait()
+		p.idle--
+	}
+
+	j := p.queue[0]
+	p.queue[0] = job{}
+	p.queue = p.queue[1:]
+	p.notFull.Signal()
+	return j, true
+}
+
+func (p *Pool) wakeAll() {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	p.cond.Broadcast()
+}
+
+func (p *Pool) taskDone() {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.pending--; p.pending == 0 {
+		p.done.Broadcast()
+	}
+}
+
+func (p *Pool) f(f func() error) {
+	defer func() {
+		if e := recover(); e != nil {
+			p.collectErrs(NewPanicError(e))
+		}
+	}()
+
+	if e := f(); e != nil {
+		p.collectErrs(e)
+	}
+}
+
+func (p *Pool) fWithContext(f func(ctx context.Context) error) {
+	defer func() {
+		if e := recover(); e != nil {
+			p.collectErrs(NewPanicError(e))
+		}
+	}()
+
+	p.m.Lock()
+	ctx := p.ctx
+	p.m.Unlock()
+
+	if e := f(ctx); e != nil {
+		p.collectErrs(e)
+	}
+}
+
+func (p *Pool) collectErrs(err error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	p.errs = append(p.errs, err)
+}
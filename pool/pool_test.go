@@ -1,3 +1,402 @@
 package pool
 
-//TODO：做并发测试，两个方法对比
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	counts, m := 0, sync.Mutex{}
+
+	p := NewPool(4)
+	defer p.Stop()
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, p.Submit(func() error {
+			m.Lock()
+			defer m.Unlock()
+			counts++
+			return nil
+		}))
+	}
+	assert.Empty(t, p.Wait())
+	assert.EqualValues(t, 100, counts)
+	assert.EqualValues(t, 4, p.GetWorkerNum())
+}
+
+func TestPoolErrsAndPanic(t *testing.T) {
+	p := NewPool(2)
+	defer p.Stop()
+
+	assert.NoError(t, p.Submit(func() error { return errors.New("err") }))
+	assert.NoError(t, p.Submit(func() error { panic("panic") }))
+	assert.NoError(t, p.Submit(func(ctx context.Context) error { panic("panic") }))
+	assert.NoError(t, p.Submit(func() error { return nil }))
+
+	errs := p.Wait()
+	assert.Len(t, errs, 3)
+	assert.EqualValues(t, 2, p.GetWorkerNum())
+}
+
+func panicTask() error {
+	panic(context.Canceled)
+}
+
+func TestPoolPanicStack(t *testing.T) {
+	p := NewPool(1)
+	defer p.Stop()
+
+	assert.NoError(t, p.Submit(panicTask))
+	assert.NoError(t, p.Execute(func() { panic("execute") }, nil))
+
+	errs := p.Wait()
+	assert.Len(t, errs, 2)
+
+	var pe *PanicError
+	assert.True(t, errors.As(errs[0], &pe))
+	assert.Equal(t, context.Canceled, pe.Value)
+	assert.True(t, errors.Is(errs[0], context.Canceled))
+	assert.Equal(t, context.Canceled.Error(), pe.Error())
+	//堆栈包含panic的位置
+	assert.Contains(t, string(pe.Stack), "panicTask")
+
+	assert.True(t, errors.As(errs[1], &pe))
+	assert.Equal(t, "execute", pe.Value)
+	assert.Nil(t, pe.Unwrap())
+	assert.Contains(t, string(pe.Stack), "TestPoolPanicStack")
+}
+
+func TestPoolSubmitTypeErr(t *testing.T) {
+	p := NewPool(1)
+	defer p.Stop()
+
+	assert.EqualError(t, p.Submit(func() {}), SUBMIT_F_TYPE_ERR)
+	assert.Empty(t, p.Wait())
+}
+
+func TestPoolWithContext(t *testing.T) {
+	p := NewPool(1)
+	ctx := p.WithContext(context.TODO())
+
+	assert.NoError(t, p.Submit(func(c context.Context) error {
+		assert.Equal(t, ctx, c)
+		return nil
+	}))
+	assert.Panics(t, func() { p.WithContext(context.TODO()) })
+
+	p.Stop()
+	assert.Error(t, ctx.Err())
+	assert.Empty(t, p.Wait())
+}
+
+func TestPoolStop(t *testing.T) {
+	counts, m := 0, sync.Mutex{}
+
+	p := NewPool(2)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, p.Submit(func() error {
+			time.Sleep(10 * time.Millisecond)
+			m.Lock()
+			defer m.Unlock()
+			counts++
+			return nil
+		}))
+	}
+	p.Stop()
+
+	//Stop 会执行完列队中的任务
+	assert.EqualValues(t, 10, counts)
+	assert.EqualError(t, p.Submit(func() error { return nil }), POOL_STOPPED_ERR)
+}
+
+func TestPoolGoroutineNum(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	p := NewPool(8)
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, p.Submit(func() error { return nil }))
+	}
+	p.Wait()
+	assert.True(t, runtime.NumGoroutine()-before <= 8)
+
+	p.Stop()
+	time.Sleep(10 * time.Millisecond)
+	assert.True(t, runtime.NumGoroutine() <= before)
+}
+
+func TestPoolSetWorkerNum(t *testing.T) {
+	block := make(chan struct{})
+
+	p := NewPool(2)
+	p.SetWorkerNum(6)
+	assert.EqualValues(t, 6, p.GetWorkerNum())
+
+	for i := 0; i < 6; i++ {
+		assert.NoError(t, p.Submit(func() error { <-block; return nil }))
+	}
+
+	//调小后，运行中的任务不受影响
+	p.SetWorkerNum(3)
+	assert.EqualValues(t, 6, p.GetWorkerNum())
+	close(block)
+	p.Wait()
+	for p.GetWorkerNum() != 3 {
+		runtime.Gosched()
+	}
+
+	p.SetWorkerNum(0)
+	for p.GetWorkerNum() != 1 {
+		runtime.Gosched()
+	}
+	p.Stop()
+	assert.EqualValues(t, 0, p.GetWorkerNum())
+}
+
+//占住唯一的常驻协程, 之后提交的任务都留在列队中
+func blockPool(t *testing.T, p *Pool) chan struct{} {
+	block, started := make(chan struct{}), make(chan struct{})
+	assert.NoError(t, p.Submit(func() error {
+		close(started)
+		<-block
+		return nil
+	}))
+	<-started
+	return block
+}
+
+func TestPoolFullBlock(t *testing.T) {
+	p := NewPool(1)
+	defer p.Stop()
+	p.SetQueueSize(1, FullBlock)
+	block := blockPool(t, p)
+
+	assert.NoError(t, p.Submit(func() error { return nil }))
+	submitted := make(chan struct{})
+	go func() {
+		assert.NoError(t, p.Submit(func() error { return nil }))
+		close(submitted)
+	}()
+
+	select {
+	case <-submitted:
+		t.Fatal("p.Submit() should block while the queue is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(block)
+	<-submitted
+	assert.Empty(t, p.Wait())
+}
+
+func TestPoolFullReject(t *testing.T) {
+	p := NewPool(1)
+	defer p.Stop()
+	p.SetQueueSize(1, FullReject)
+	block := blockPool(t, p)
+
+	assert.NoError(t, p.Submit(func() error { return nil }))
+	assert.Equal(t, ErrPoolFull, p.Submit(func() error { return nil }))
+	assert.Equal(t, ErrPoolFull, p.Execute(func() {}, nil))
+
+	close(block)
+	assert.Empty(t, p.Wait())
+}
+
+func TestPoolFullDrop(t *testing.T) {
+	var order []int
+	m := sync.Mutex{}
+	task := func(i int) func() error {
+		return func() error {
+			m.Lock()
+			defer m.Unlock()
+			order = append(order, i)
+			return nil
+		}
+	}
+
+	p := NewPool(1)
+	defer p.Stop()
+	p.SetQueueSize(2, FullDropOldest)
+	block := blockPool(t, p)
+
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, p.Submit(task(i)))
+	}
+	p.SetQueueSize(2, FullDropNewest)
+	assert.NoError(t, p.Submit(task(4)))
+
+	//被丢弃的任务通过drop通知调用者
+	var dropped error
+	assert.NoError(t, p.Execute(func() { order = append(order, 5) }, func(err error) { dropped = err }))
+	assert.Equal(t, ErrTaskDropped, dropped)
+
+	close(block)
+	assert.Equal(t, []error{ErrTaskDropped, ErrTaskDropped, ErrTaskDropped}, p.Wait())
+	assert.Equal(t, []int{2, 3}, order)
+}
+
+func TestPoolFullCallerRuns(t *testing.T) {
+	p := NewPool(1)
+	defer p.Stop()
+	p.SetQueueSize(1, FullCallerRuns)
+	block := blockPool(t, p)
+
+	assert.NoError(t, p.Submit(func() error { return nil }))
+	ran := false
+	assert.NoError(t, p.Submit(func() error { ran = true; return errors.New("err") }))
+	//在调用者的协程中已经运行完毕
+	assert.True(t, ran)
+
+	close(block)
+	assert.Len(t, p.Wait(), 1)
+}
+
+func TestPoolFullStop(t *testing.T) {
+	p := NewPool(1)
+	p.SetQueueSize(1, FullBlock)
+	block := blockPool(t, p)
+	assert.NoError(t, p.Submit(func() error { return nil }))
+
+	errs := make(chan error)
+	go func() { errs <- p.Submit(func() error { return nil }) }()
+	time.Sleep(10 * time.Millisecond)
+
+	go p.Stop()
+	assert.EqualError(t, <-errs, POOL_STOPPED_ERR)
+	close(block)
+	p.Wait()
+}
+
+func waitWorkerNum(p *Pool, n uint64) {
+	for p.GetWorkerNum() != n {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPoolElastic(t *testing.T) {
+	p := NewPool(8)
+	defer p.Stop()
+	assert.EqualValues(t, 8, p.GetWorkerNum())
+
+	//空闲超时后只保留最少数量
+	p.SetMinWorkerNum(2, 20*time.Millisecond)
+	waitWorkerNum(p, 2)
+
+	//负载上升时增加协程, 不超过最大数量
+	block := make(chan struct{})
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, p.Submit(func() error { <-block; return nil }))
+	}
+	assert.EqualValues(t, 8, p.GetWorkerNum())
+
+	close(block)
+	assert.Empty(t, p.Wait())
+	waitWorkerNum(p, 2)
+
+	p.SetMinWorkerNum(0, 0)
+	waitWorkerNum(p, 0)
+	assert.NoError(t, p.Submit(func() error { return nil }))
+	assert.Empty(t, p.Wait())
+}
+
+func TestPoolElasticStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	p := NewPool(4)
+	p.SetMinWorkerNum(1, time.Hour)
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, p.Submit(func() error { return nil }))
+	}
+	p.Wait()
+	p.Stop()
+
+	assert.EqualValues(t, 0, p.GetWorkerNum())
+	time.Sleep(10 * time.Millisecond)
+	assert.True(t, runtime.NumGoroutine() <= before)
+}
+
+func TestPoolSubmitPriority(t *testing.T) {
+	var order []int
+	m := sync.Mutex{}
+	task := func(i int) func() error {
+		return func() error {
+			m.Lock()
+			defer m.Unlock()
+			order = append(order, i)
+			return nil
+		}
+	}
+
+	p := NewPool(1)
+	defer p.Stop()
+	block := blockPool(t, p)
+
+	assert.NoError(t, p.Submit(task(0)))
+	assert.NoError(t, p.SubmitPriority(5, task(1)))
+	assert.NoError(t, p.SubmitPriority(-1, task(2)))
+	assert.NoError(t, p.SubmitPriority(5, task(3)))
+	assert.NoError(t, p.ExecutePriority(9, func() { task(4)() }, nil))
+
+	close(block)
+	p.Wait()
+	assert.Equal(t, []int{4, 1, 3, 0, 2}, order)
+}
+
+func TestPoolDropOldestPriority(t *testing.T) {
+	var order []int
+	m := sync.Mutex{}
+	task := func(i int) func() error {
+		return func() error {
+			m.Lock()
+			defer m.Unlock()
+			order = append(order, i)
+			return nil
+		}
+	}
+
+	p := NewPool(1)
+	defer p.Stop()
+	p.SetQueueSize(3, FullDropOldest)
+	block := blockPool(t, p)
+
+	assert.NoError(t, p.SubmitPriority(1, task(0)))
+	assert.NoError(t, p.Submit(task(1)))
+	assert.NoError(t, p.Submit(task(2)))
+	//丢弃优先级最低的任务中最早的一个
+	assert.NoError(t, p.SubmitPriority(1, task(3)))
+
+	close(block)
+	assert.Equal(t, []error{ErrTaskDropped}, p.Wait())
+	assert.Equal(t, []int{0, 3, 2}, order)
+}
+
+//go test -run=^$ -bench=. -benchmem ./pool
+//与 group 的对比见 group/bench_test.go
+func BenchmarkPool(b *testing.B) {
+	for _, size := range []int{1, 16, 256} {
+		for _, task := range []struct {
+			name string
+			f    func() error
+		}{
+			{"empty", func() error { return nil }},
+			{"sleep", func() error { time.Sleep(50 * time.Microsecond); return nil }},
+		} {
+			task := task
+			b.Run(fmt.Sprintf("workers=%d/%s", size, task.name), func(b *testing.B) {
+				p := NewPool(uint64(size))
+				defer p.Stop()
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					p.Submit(task.f)
+				}
+				p.Wait()
+			})
+		}
+	}
+}